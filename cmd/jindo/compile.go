@@ -0,0 +1,67 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"jindo-tool/internal/command"
+	"jindo/pkg/jindo/compile"
+	"jindo/pkg/jindo/sema"
+)
+
+// runCompile implements "jindo compile [-force-space] [-q] [-unused-imports-warn] [dir]".
+// It loads the space in dir (the current directory by default), resolves
+// it and checks that all referenced types exist and all imports are used.
+// Unused imports are errors unless -unused-imports-warn is set, which
+// reports them as warnings instead. Unless -q is set, it reports the name
+// of the space and its number of files.
+func runCompile(args []string) {
+	fs := flag.NewFlagSet("compile", flag.ExitOnError)
+	forceSpace := fs.Bool("force-space", false, "use the first file's space name for all files, with a warning")
+	quiet := fs.Bool("q", false, "do not report the space being compiled")
+	unusedWarn := fs.Bool("unused-imports-warn", false, "report unused imports as warnings rather than errors")
+	fs.Parse(args)
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	conf := &compile.Config{
+		ForceSpace: *forceSpace,
+		Warn:       func(err error) { command.Warnf("%v", err) },
+	}
+	if verbose {
+		conf.Trace = command.Stderr
+	}
+	s, err := compile.LoadSpace(dir, conf)
+	if err != nil {
+		command.Fatalf("%v", err)
+	}
+	if !*quiet {
+		files := "files"
+		if len(s.Files) == 1 {
+			files = "file"
+		}
+		fmt.Fprintf(command.Stdout, "space %s: %d %s\n", s.Name, len(s.Files), files)
+	}
+
+	info, errs := sema.Resolve(s.Files)
+	errs = append(errs, sema.CheckTypes(s.Files)...)
+	errs = append(errs, sema.CheckOpers(s.Files)...)
+	for _, err := range errs {
+		command.Errorf("%v", err)
+	}
+	for _, err := range sema.UnusedImports(s.Files, info) {
+		if *unusedWarn {
+			command.Warnf("%v", err)
+		} else {
+			command.Errorf("%v", err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package main
+
+import (
+	"io"
+	"jindo-tool/internal/command"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompileReportsSpace(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"a.paw": "space geom\n\nfunc f() {}\n",
+		"b.paw": "space geom\n\nfunc g() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(w io.Writer) { command.Stdout = w }(command.Stdout)
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{dir}, "space geom: 2 files\n"},
+		{[]string{"-q", dir}, ""},
+	} {
+		var buf strings.Builder
+		command.Stdout = &buf
+		runCompile(test.args)
+		if got := buf.String(); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.args, got, test.want)
+		}
+	}
+	if n := command.GetExitStatus(); n != 0 {
+		t.Errorf("got exit status %d, want 0", n)
+	}
+}
+
+func TestCompileVerbose(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.paw"), []byte("space geom\n\nfunc f() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(stdout, stderr io.Writer, v bool) {
+		command.Stdout, command.Stderr, verbose = stdout, stderr, v
+	}(command.Stdout, command.Stderr, verbose)
+	var stdout, stderr strings.Builder
+	command.Stdout, command.Stderr = &stdout, &stderr
+
+	verbose = true
+	runCompile([]string{"-q", dir})
+	if !strings.Contains(stderr.String(), "funcDecl (") {
+		t.Errorf("got trace %q, want one of funcDecl", stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("got output %q, want none", stdout.String())
+	}
+
+	stderr.Reset()
+	verbose = false
+	runCompile([]string{"-q", dir})
+	if stderr.Len() != 0 {
+		t.Errorf("got trace %q without -v, want none", stderr.String())
+	}
+}
+
+func TestCompileUnusedImportsWarn(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.paw"), []byte("space geom\n\nimport \"io\"\n\nfunc f() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(w io.Writer) { command.Stderr = w }(command.Stderr)
+	var stderr strings.Builder
+	command.Stderr = &stderr
+
+	status := command.GetExitStatus()
+	runCompile([]string{"-q", "-unused-imports-warn", dir})
+	if want := "a.paw:3:8: \"io\" imported and not used\n"; !strings.HasPrefix(stderr.String(), "warning: ") || !strings.HasSuffix(stderr.String(), want) {
+		t.Errorf("got %q, want a warning ending in %q", stderr.String(), want)
+	}
+	if n := command.GetExitStatus(); n != status {
+		t.Errorf("got exit status %d, want %d", n, status)
+	}
+}
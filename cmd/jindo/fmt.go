@@ -0,0 +1,49 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"jindo-tool/internal/command"
+	"jindo/pkg/jindo/format"
+	"os"
+)
+
+// runFmt implements "jindo fmt [-w] files...". Without -w the
+// formatted source is written to standard output.
+func runFmt(args []string) {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write result to source file instead of stdout")
+	fs.Parse(args)
+
+	for _, filename := range fs.Args() {
+		if err := fmtFile(filename, *write); err != nil {
+			command.Errorf("%v", err)
+		}
+	}
+}
+
+func fmtFile(filename string, write bool) error {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	res, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s:%w", filename, err)
+	}
+	if !write {
+		_, err = command.Stdout.Write(res)
+		return err
+	}
+	if bytes.Equal(src, res) {
+		return nil
+	}
+	return os.WriteFile(filename, res, 0o644)
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+// Package command holds the state shared by the jindo commands:
+// error and warning reporting, the exit status, and functions
+// to run at exit.
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Stdout is where the output of commands is written.
+var Stdout io.Writer = os.Stdout
+
+// Stderr is where errors and warnings are reported.
+var Stderr io.Writer = os.Stderr
+
+var (
+	mu          sync.Mutex
+	exitStatus  = 0
+	atExitFuncs []func()
+	nerrors     int // number of errors reported
+	nwarnings   int // number of warnings reported
+)
+
+// SetExitStatus raises the exit status to n, if it is lower.
+func SetExitStatus(n int) {
+	mu.Lock()
+	if exitStatus < n {
+		exitStatus = n
+	}
+	mu.Unlock()
+}
+
+// GetExitStatus returns the current exit status.
+func GetExitStatus() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return exitStatus
+}
+
+// AtExit registers f to be run by Exit, after the
+// functions registered before it.
+func AtExit(f func()) {
+	mu.Lock()
+	atExitFuncs = append(atExitFuncs, f)
+	mu.Unlock()
+}
+
+// RunAtExit runs and unregisters the functions registered with AtExit.
+func RunAtExit() {
+	mu.Lock()
+	funcs := atExitFuncs
+	atExitFuncs = nil
+	mu.Unlock()
+	for _, f := range funcs {
+		f()
+	}
+}
+
+// Exit runs the functions registered with AtExit and
+// terminates the program with the current exit status.
+func Exit() {
+	RunAtExit()
+	os.Exit(GetExitStatus())
+}
+
+// Errorf reports an error and sets the exit status to 1.
+func Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(Stderr, format, args...)
+	fmt.Fprintln(Stderr)
+	mu.Lock()
+	nerrors++
+	mu.Unlock()
+	SetExitStatus(1)
+}
+
+// Warnf reports a warning. Unlike Errorf, it leaves the exit status unchanged.
+func Warnf(format string, args ...interface{}) {
+	fmt.Fprintf(Stderr, "warning: "+format, args...)
+	fmt.Fprintln(Stderr)
+	mu.Lock()
+	nwarnings++
+	mu.Unlock()
+}
+
+// Summary returns a summary of the reported diagnostics such as
+// "2 warnings, 1 error", or "" if none were reported.
+func Summary() string {
+	mu.Lock()
+	defer mu.Unlock()
+	if nwarnings == 0 && nerrors == 0 {
+		return ""
+	}
+	return plural(nwarnings, "warning") + ", " + plural(nerrors, "error")
+}
+
+// PrintSummary prints the Summary, if any. It is meant to be run at exit:
+//
+//	command.AtExit(command.PrintSummary)
+func PrintSummary() {
+	if s := Summary(); s != "" {
+		fmt.Fprintln(Stderr, s)
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Fatalf reports an error and exits.
+func Fatalf(format string, args ...interface{}) {
+	Errorf(format, args...)
+	Exit()
+}
+
+// ExitIfErrors exits if any error was reported.
+func ExitIfErrors() {
+	if GetExitStatus() != 0 {
+		Exit()
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWarnings(t *testing.T) {
+	var buf strings.Builder
+	Stderr = &buf
+
+	Warnf("unused %s", "x")
+	Warnf("unused %s", "y")
+	if got := GetExitStatus(); got != 0 {
+		t.Errorf("got exit status %d after warnings, want 0", got)
+	}
+	if got, want := Summary(), "2 warnings, 0 errors"; got != want {
+		t.Errorf("got summary %q, want %q", got, want)
+	}
+
+	Errorf("undefined: %s", "z")
+	if got := GetExitStatus(); got != 1 {
+		t.Errorf("got exit status %d after an error, want 1", got)
+	}
+
+	PrintSummary()
+	want := "warning: unused x\nwarning: unused y\nundefined: z\n2 warnings, 1 error\n"
+	if buf.String() != want {
+		t.Errorf("got output %q, want %q", buf.String(), want)
+	}
+}
@@ -7,10 +7,103 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"jindo/pkg/jindo/parser"
+	"jindo-tool/internal/command"
+	"os"
+	"runtime"
+	"runtime/pprof"
 )
 
+var (
+	cpuProfile = flag.String("cpuprofile", "", "write a CPU profile to `file`")
+	memProfile = flag.String("memprofile", "", "write a memory profile to `file` on exit")
+	verbose    bool
+)
+
+func init() {
+	flag.BoolVar(&verbose, "v", false, "write a trace of the parser to standard error")
+	flag.BoolVar(&verbose, "verbose", false, "same as -v")
+}
+
+// commands maps command names to their implementations.
+var commands = map[string]func(args []string){
+	"compile": runCompile,
+	"fmt":     runFmt,
+	"repl":    runRepl,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: jindo [flags] <command> [arguments]\n\n")
+	fmt.Fprintf(os.Stderr, "The commands are:\n\n")
+	fmt.Fprintf(os.Stderr, "\tcompile    compile the space in a directory\n")
+	fmt.Fprintf(os.Stderr, "\tfmt        reformat source files\n")
+	fmt.Fprintf(os.Stderr, "\trepl       evaluate expressions read from standard input\n")
+	fmt.Fprintf(os.Stderr, "\nThe flags are:\n\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
 func main() {
-	fmt.Println(parser.ParseFile("", nil))
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		usage()
+	}
+
+	run := commands[args[0]]
+	if run == nil {
+		fmt.Fprintf(os.Stderr, "jindo %s: unknown command\n", args[0])
+		usage()
+	}
+	invoke(run, args[1:])
+}
+
+// invoke runs a command with the global flags applied
+// and exits once it returns.
+func invoke(run func(args []string), args []string) {
+	command.AtExit(command.PrintSummary)
+	if err := startProfiling(*cpuProfile, *memProfile); err != nil {
+		command.Fatalf("jindo: %v", err)
+	}
+	run(args)
+	command.Exit()
+}
+
+// startProfiling starts CPU profiling to cpu, if not empty, and arranges
+// for the profiles to be written to cpu and mem, if not empty, at exit.
+func startProfiling(cpu, mem string) error {
+	if cpu != "" {
+		f, err := os.Create(cpu)
+		if err != nil {
+			return err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		command.AtExit(func() {
+			pprof.StopCPUProfile()
+			if err := f.Close(); err != nil {
+				command.Errorf("jindo: writing CPU profile: %v", err)
+			}
+		})
+	}
+	if mem != "" {
+		command.AtExit(func() {
+			f, err := os.Create(mem)
+			if err != nil {
+				command.Errorf("jindo: %v", err)
+				return
+			}
+			runtime.GC() // get up-to-date statistics
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				command.Errorf("jindo: writing memory profile: %v", err)
+			}
+			f.Close()
+		})
+	}
+	return nil
 }
@@ -0,0 +1,33 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package main
+
+import (
+	"jindo-tool/internal/command"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfiling(t *testing.T) {
+	dir := t.TempDir()
+	cpu := filepath.Join(dir, "cpu.prof")
+	mem := filepath.Join(dir, "mem.prof")
+	if err := startProfiling(cpu, mem); err != nil {
+		t.Fatal(err)
+	}
+	command.RunAtExit()
+
+	for _, filename := range []string{cpu, mem} {
+		info, err := os.Stat(filename)
+		if err != nil {
+			t.Error(err)
+		} else if info.Size() == 0 {
+			t.Errorf("%s is empty", filename)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"jindo-tool/internal/command"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/parser"
+	"os"
+	"strings"
+)
+
+// runRepl implements "jindo repl". It reads expressions from standard
+// input, one comma-separated list per line, and prints each with its
+// constants folded, until the end of the input.
+func runRepl(args []string) {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	fs.Parse(args)
+
+	prompt := ""
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		prompt = "> "
+	}
+	if err := repl(os.Stdin, command.Stdout, prompt); err != nil {
+		command.Errorf("jindo repl: %v", err)
+	}
+}
+
+// repl evaluates the lines of r, writing the prompt before each
+// line and the results or syntax errors to w. Errors are reported as
+// "line:col: msg", with lines counted from the start of r.
+func repl(r io.Reader, w io.Writer, prompt string) error {
+	s := bufio.NewScanner(r)
+	for line := 1; ; line++ {
+		fmt.Fprint(w, prompt)
+		if !s.Scan() {
+			break
+		}
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+
+		list, err := parser.ParseExprList(s.Text())
+		if e, ok := err.(parser.Error); ok {
+			fmt.Fprintf(w, "%d:%d: %s\n", line, e.Pos.Col(), e.Msg)
+			continue
+		} else if err != nil {
+			fmt.Fprintf(w, "%d: %v\n", line, err)
+			continue
+		}
+		results := make([]string, len(list))
+		for i, x := range list {
+			results[i] = parser.String(ast.Fold(x))
+		}
+		fmt.Fprintln(w, strings.Join(results, ", "))
+	}
+	if prompt != "" {
+		fmt.Fprintln(w)
+	}
+	return s.Err()
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"2 + 3\n", "5\n"},
+		{"2 + 3", "5\n"},
+		{"7 / 2, 7 / 2.0, x + 1\n", "3, 3.5, x + 1\n"},
+		{"1 +\n\n(4)\n", "1:4: syntax error: unexpected fileOrEof, expecting expression\n4\n"},
+	} {
+		var out strings.Builder
+		if err := repl(strings.NewReader(test.in), &out, ""); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%q: got %q, want %q", test.in, out.String(), test.want)
+		}
+	}
+
+	var out strings.Builder
+	if err := repl(strings.NewReader("2 + 3\n"), &out, "> "); err != nil {
+		t.Fatal(err)
+	}
+	if want := "> 5\n> \n"; out.String() != want {
+		t.Errorf("with prompt: got %q, want %q", out.String(), want)
+	}
+}
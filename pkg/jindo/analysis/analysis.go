@@ -0,0 +1,35 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+// Package analysis implements lint checks over jindo syntax trees.
+package analysis
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/position"
+)
+
+// Diagnostic describes a warning reported by a check.
+type Diagnostic struct {
+	Pos position.Pos
+	Msg string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: warning: %s", d.Pos, d.Msg)
+}
+
+// inspectStmts calls f for every statement in the function and
+// operator bodies of file, blocks included, in source order.
+func inspectStmts(file *ast.File, f func(ast.Stmt)) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		if s, ok := n.(ast.Stmt); ok {
+			f(s)
+		}
+		return true
+	})
+}
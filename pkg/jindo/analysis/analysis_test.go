@@ -0,0 +1,76 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package analysis
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/parser"
+	"jindo/pkg/jindo/position"
+	"strings"
+	"testing"
+)
+
+func parseString(t *testing.T, src string) *ast.File {
+	t.Helper()
+	f, err := parser.Parse(position.NewFileBase("test.jindo"), strings.NewReader(src), func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCheckEmptyBody(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\tif c {}\n}\n")
+	diags := CheckEmptyBody(f)
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %v", len(diags), diags)
+	}
+	if got, want := diags[0].Msg, "empty if body"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := diags[0].Pos.Line(); got != 3 {
+		t.Errorf("got line %d, want 3", got)
+	}
+
+	f = parseString(t, "space main\nfunc f() {\n\tif c {\n\t\treturn\n\t}\n}\n")
+	if diags := CheckEmptyBody(f); len(diags) != 0 {
+		t.Errorf("got unexpected diagnostics %v", diags)
+	}
+
+	// bodies nested in case clauses are checked too
+	f = parseString(t, "space main\nfunc f() {\n\tswitch x {\n\tcase 1:\n\t\twhile c {}\n\t\tif d {}\n\t}\n}\n")
+	var got []string
+	for _, d := range CheckEmptyBody(f) {
+		got = append(got, fmt.Sprintf("%d: %s", d.Pos.Line(), d.Msg))
+	}
+	if want := []string{"5: empty while body", "6: empty if body"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// and so are labeled loops
+	f = parseString(t, "space main\nfunc f() {\nL:\n\twhile c {}\n}\n")
+	if diags := CheckEmptyBody(f); len(diags) != 1 || diags[0].Msg != "empty while body" {
+		t.Errorf("got %v, want one empty while body", diags)
+	}
+}
+
+func TestCheckReceiverNames(t *testing.T) {
+	a := parseString(t, "space main\noper (v Vec) add (w Vec) Vec {\n\treturn v\n}\nfunc (v *Vec) Len() int {\n\treturn 0\n}\noper (p Point) add (q Point) Point {\n\treturn p\n}\n")
+	if diags := CheckReceiverNames([]*ast.File{a}); len(diags) != 0 {
+		t.Errorf("got unexpected diagnostics %v", diags)
+	}
+
+	b := parseString(t, "space main\noper (u Vec) sub (w Vec) Vec {\n\treturn u\n}\noper (_ Vec) mul (w Vec) Vec {\n\treturn w\n}\n")
+	diags := CheckReceiverNames([]*ast.File{a, b})
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %v", len(diags), diags)
+	}
+	if got, want := diags[0].String(), "test.jindo:2:7: warning: receiver name u should be consistent with receiver name v for Vec at test.jindo:2:7"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package analysis
+
+import "jindo/pkg/jindo/ast"
+
+// CheckEmptyBody reports if, for and while statements whose body is
+// empty, which is often a mistake such as a misplaced semicolon.
+//
+// TODO: allow suppressing the warning with a comment directive once
+// the parser attaches comments to nodes.
+func CheckEmptyBody(f *ast.File) []Diagnostic {
+	var list []Diagnostic
+	report := func(kind string, b *ast.BlockStmt) {
+		if b != nil && len(b.StmtList) == 0 {
+			list = append(list, Diagnostic{b.GetPos(), "empty " + kind + " body"})
+		}
+	}
+	inspectStmts(f, func(s ast.Stmt) {
+		switch s := s.(type) {
+		case *ast.IfStmt:
+			report("if", s.Block)
+		case *ast.ForStmt:
+			report("for", s.Body)
+		case *ast.WhileStmt:
+			report("while", s.Body)
+		}
+	})
+	return list
+}
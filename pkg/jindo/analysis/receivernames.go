@@ -0,0 +1,62 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package analysis
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/ast"
+)
+
+// CheckReceiverNames reports operator declarations and methods of the
+// files of a space whose receiver name differs from the one used by the
+// first of them declared on the same type, in source order.
+func CheckReceiverNames(files []*ast.File) []Diagnostic {
+	var list []Diagnostic
+	first := make(map[string]*ast.Name) // first receiver name by type
+	for _, f := range files {
+		for _, d := range f.DeclList {
+			var recv *ast.Field
+			switch d := d.(type) {
+			case *ast.OperDecl:
+				recv = d.TypeL
+			case *ast.FuncDecl:
+				recv = d.Recv
+			}
+			if recv == nil || recv.Name == nil || recv.Name.Value == "_" {
+				continue
+			}
+			typ := recvTypeName(recv.Type)
+			if typ == "" {
+				continue
+			}
+			prev := first[typ]
+			if prev == nil {
+				first[typ] = recv.Name
+				continue
+			}
+			if recv.Name.Value != prev.Value {
+				msg := fmt.Sprintf("receiver name %s should be consistent with receiver name %s for %s at %s", recv.Name.Value, prev.Value, typ, prev.Pos)
+				list = append(list, Diagnostic{recv.Name.Pos, msg})
+			}
+		}
+	}
+	return list
+}
+
+// recvTypeName returns the name of the type of a receiver, ignoring
+// pointer indirections, or "" if it is not a type name.
+func recvTypeName(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.Name:
+		return x.Value
+	case *ast.PointerType:
+		return recvTypeName(x.Elem)
+	case *ast.ParenExpr:
+		return recvTypeName(x.X)
+	}
+	return ""
+}
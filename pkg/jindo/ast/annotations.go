@@ -0,0 +1,59 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast
+
+// Annotations holds facts attached to nodes by analysis passes. Each
+// fact is stored under a node and a Key, so passes that use distinct
+// keys can annotate the same nodes without interfering. The zero value
+// is an empty set of annotations ready to use.
+type Annotations struct {
+	m map[annotation]any
+}
+
+type annotation struct {
+	node Node
+	key  any // *Key[T]
+}
+
+// A Key identifies one kind of fact, of type T, in Annotations. Keys
+// are compared by identity; create them with NewKey.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new key for facts of type T. The name is only used
+// for debugging.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name}
+}
+
+// String returns the name of k.
+func (k *Key[T]) String() string { return k.name }
+
+// Set attaches the fact v under k to node n, replacing any previous
+// fact for k on n.
+func (k *Key[T]) Set(a *Annotations, n Node, v T) {
+	if a.m == nil {
+		a.m = make(map[annotation]any)
+	}
+	a.m[annotation{n, k}] = v
+}
+
+// Get returns the fact attached under k to node n, and whether there
+// was one.
+func (k *Key[T]) Get(a *Annotations, n Node) (v T, ok bool) {
+	x, ok := a.m[annotation{n, k}]
+	if ok {
+		v = x.(T)
+	}
+	return v, ok
+}
+
+// Delete removes the fact attached under k to node n, if any.
+func (k *Key[T]) Delete(a *Annotations, n Node) {
+	delete(a.m, annotation{n, k})
+}
@@ -9,10 +9,12 @@ package ast
 import (
 	"jindo/pkg/jindo/position"
 	"jindo/pkg/jindo/token"
+	"strings"
 )
 
 type Node interface {
 	GetPos() position.Pos
+	End() position.Pos // position of the first character immediately after the node
 	aNode()
 	SetPos(pos position.Pos)
 }
@@ -28,12 +30,24 @@ func (n *node) SetPos(pos position.Pos) {
 }
 
 type File struct {
-	SpaceName *Name
+	Doc       []Comment // comment block immediately preceding the space clause; or nil
+	SpaceName *Name     // nil means the space clause is missing
 	DeclList  []Decl
 	EOF       position.Pos
 	node
 }
 
+// A Comment is a //-style or /*-style comment.
+type Comment struct {
+	Pos  position.Pos
+	Text string // comment text, including the comment markers
+}
+
+// EndLine returns the line on which c ends.
+func (c Comment) EndLine() uint {
+	return c.Pos.Line() + uint(strings.Count(c.Text, "\n"))
+}
+
 // Top Level Declarations
 type (
 	Decl interface {
@@ -42,9 +56,11 @@ type (
 	}
 
 	//              Path
+	// LocalName    Path
 	ImportDecl struct {
-		Group *Group    // nil means not part of a group
-		Path  *BasicLit // Path.Bad || Path.Kind == StringLit; nil means no path
+		Group     *Group    // nil means not part of a group
+		LocalName *Name     // nil means no rename
+		Path      *BasicLit // Path.Bad || Path.Kind == StringLit; nil means no path
 		decl
 	}
 
@@ -65,20 +81,30 @@ type (
 		decl
 	}
 
-	VarDecl struct {
+	ConstDecl struct {
 		Group    *Group // nil means not part of a group
 		NameList *Name
 		Type     Expr // nil means no type
-		Values   Expr // nil means no values
+		Values   Expr
+		decl
+	}
+
+	VarDecl struct {
+		Group    *Group // nil means not part of a group
+		NameList []*Name
+		Type     Expr // nil means no type
+		Values   Expr // nil means no values; *ListExpr for more than one value
 		decl
 	}
 
 	FuncDecl struct {
 		Group  *Group // nil means not part of a group
+		Recv   *Field // nil means regular function
 		Param  []*Field
 		Name   *Name // identifier
-		Return Expr  // nil means no return type
+		Return Expr  // nil means no return type; *ListExpr for a parenthesized result list
 		Body   *BlockStmt
+		Rparen position.Pos // position of the ")" closing the parameters
 		decl
 	}
 )
@@ -87,13 +113,59 @@ type decl struct{ node }
 
 func (*decl) aDecl() {}
 
+// NewName returns a name with the given position and value, marked
+// with the NameKind of the predeclared identifier it spells, if any.
 func NewName(pos position.Pos, value string) *Name {
 	n := new(Name)
 	n.Pos = pos
 	n.Value = value
+	n.Kind = predeclaredNames[value]
 	return n
 }
 
+// A NameKind tells whether a name spells one of the predeclared
+// identifiers true, false and nil. It says nothing about what the name
+// refers to: a space may still declare its own true, which shadows the
+// predeclared one, and it is up to later passes to report that.
+type NameKind uint8
+
+const (
+	PlainName NameKind = iota
+	TrueName
+	FalseName
+	NilName
+)
+
+var predeclaredNames = map[string]NameKind{
+	"true":  TrueName,
+	"false": FalseName,
+	"nil":   NilName,
+}
+
+// IsPredeclared reports whether k is the kind of a predeclared identifier.
+func (k NameKind) IsPredeclared() bool { return k != PlainName }
+
+// NewBadExpr returns a bad expression at pos, recording why it could
+// not be parsed.
+func NewBadExpr(pos position.Pos, reason string) *BadExpr {
+	x := new(BadExpr)
+	x.Pos = pos
+	x.reason = reason
+	return x
+}
+
+// Reason returns why x could not be parsed, as given to NewBadExpr,
+// or "" if unknown.
+func (x *BadExpr) Reason() string { return x.reason }
+
+// IsRune reports whether x is a rune literal such as 'a', whose
+// value is an integer code point.
+func (x *BasicLit) IsRune() bool { return x.Kind == token.RuneLit }
+
+// IsImaginary reports whether x is an imaginary literal such as 3i or
+// 1.5i, whose value is complex.
+func (x *BasicLit) IsImaginary() bool { return x.Kind == token.ImagLit }
+
 type StmtType uint8
 
 const (
@@ -134,19 +206,33 @@ type (
 	}
 
 	IncDecStmt struct {
-		X   Expr
-		Tok token.Token
+		X  Expr
+		Op token.Operator // Add for X++, Sub for X--
 		simpleStmt
 	}
 
 	ContinueStmt struct {
+		Label *Name // nil means no label
 		simpleStmt
 	}
 
 	BreakStmt struct {
+		Label *Name // nil means no label
 		simpleStmt
 	}
 
+	GotoStmt struct {
+		Label *Name
+		stmt
+	}
+
+	// Label: Stmt
+	LabeledStmt struct {
+		Label *Name
+		Stmt  Stmt
+		stmt
+	}
+
 	ReturnStmt struct {
 		Result Expr
 		stmt
@@ -154,19 +240,20 @@ type (
 
 	DeclStmt struct {
 		DeclList []Decl
+		Rparen   position.Pos // position of the ")" closing a grouped declaration; unknown otherwise
 		stmt
 	}
 
 	DefineStmt struct {
-		Lhs Expr
-		Rhs Expr
+		Lhs []Expr
+		Rhs []Expr
 		simpleStmt
 	}
 
 	AssignStmt struct {
-		Lhs Expr
+		Lhs []Expr
 		Op  token.Operator
-		Rhs Expr
+		Rhs []Expr
 		simpleStmt
 	}
 
@@ -191,6 +278,21 @@ type (
 		stmt
 	}
 
+	SwitchStmt struct {
+		Init   SimpleStmt
+		Tag    Expr // nil means a tagless switch
+		Body   []*CaseClause
+		Rbrace position.Pos
+		stmt
+	}
+
+	CaseClause struct {
+		CaseList []Expr // nil means default clause
+		Body     []Stmt
+		Colon    position.Pos
+		node
+	}
+
 	simpleStmt struct {
 		stmt
 	}
@@ -230,6 +332,7 @@ type (
 	// Value
 	Name struct {
 		Value string
+		Kind  NameKind // set by NewName
 		expr
 	}
 
@@ -241,9 +344,28 @@ type (
 		expr
 	}
 
+	// []ElemType { Elems[0], Elems[1], ... }
+	// [...]ElemType { Elems[0], Elems[1], ... }
 	SliceLit struct {
 		ElemType Expr
 		Elems    []Expr
+		Rbrace   position.Pos
+		Dots     bool // [...]ElemType: length inferred from the elements
+		expr
+	}
+
+	// Type { ElemList[0], ElemList[1], ... }
+	CompositeLit struct {
+		Type     Expr
+		ElemList []Expr
+		NKeys    int // number of elements with keys
+		Rbrace   position.Pos
+		expr
+	}
+
+	// Key: Value
+	KeyValueExpr struct {
+		Key, Value Expr
 		expr
 	}
 
@@ -254,7 +376,8 @@ type (
 	}
 
 	ParenExpr struct {
-		X Expr
+		X      Expr
+		Rparen position.Pos
 		expr
 	}
 	SliceType struct {
@@ -262,6 +385,38 @@ type (
 		expr
 	}
 
+	// map[Key]Value
+	MapType struct {
+		Key, Value Expr
+		expr
+	}
+
+	// *Elem
+	PointerType struct {
+		Elem Expr
+		expr
+	}
+
+	// ...Elem
+	DotsType struct {
+		Elem Expr
+		expr
+	}
+
+	// struct { FieldList[0]; FieldList[1]; ... }
+	StructType struct {
+		FieldList []*Field
+		Rbrace    position.Pos
+		expr
+	}
+
+	// ElemList[0], ElemList[1], ...
+	ListExpr struct {
+		ElemList []Expr
+		Rparen   position.Pos // position of the ")" closing a parenthesized result list; unknown otherwise
+		expr
+	}
+
 	// X.Sel
 	SelectorExpr struct {
 		X   Expr
@@ -270,8 +425,20 @@ type (
 	}
 
 	IndexExpr struct {
-		X     Expr
-		Index Expr
+		X      Expr
+		Index  Expr
+		Rbrack position.Pos
+		expr
+	}
+
+	// X[Low:High] or X[Low:High:Max]
+	SliceExpr struct {
+		X      Expr
+		Low    Expr // nil means omitted
+		High   Expr // nil means omitted
+		Max    Expr // nil unless Full
+		Full   bool // 3-index slice (X[Low:High:Max])
+		Rbrack position.Pos
 		expr
 	}
 
@@ -279,6 +446,7 @@ type (
 	CallExpr struct {
 		Func    Expr
 		ArgList []Expr // nil means no arguments
+		Rparen  position.Pos
 		expr
 	}
 
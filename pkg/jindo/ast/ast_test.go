@@ -0,0 +1,563 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast_test
+
+import (
+	"flag"
+	"fmt"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/parser"
+	"jindo/pkg/jindo/position"
+	"jindo/pkg/jindo/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the tests")
+
+// Every node type implements the interface of its category.
+var (
+	_ ast.Node = (*ast.File)(nil)
+	_ ast.Node = (*ast.CaseClause)(nil)
+
+	_ ast.Decl = (*ast.ImportDecl)(nil)
+	_ ast.Decl = (*ast.OperDecl)(nil)
+	_ ast.Decl = (*ast.TypeDecl)(nil)
+	_ ast.Decl = (*ast.ConstDecl)(nil)
+	_ ast.Decl = (*ast.VarDecl)(nil)
+	_ ast.Decl = (*ast.FuncDecl)(nil)
+
+	_ ast.SimpleStmt = (*ast.ExprStmt)(nil)
+	_ ast.SimpleStmt = (*ast.EmptyStmt)(nil)
+	_ ast.SimpleStmt = (*ast.IncDecStmt)(nil)
+	_ ast.SimpleStmt = (*ast.ContinueStmt)(nil)
+	_ ast.SimpleStmt = (*ast.BreakStmt)(nil)
+	_ ast.SimpleStmt = (*ast.DefineStmt)(nil)
+	_ ast.SimpleStmt = (*ast.AssignStmt)(nil)
+	_ ast.Stmt       = (*ast.GotoStmt)(nil)
+	_ ast.Stmt       = (*ast.LabeledStmt)(nil)
+	_ ast.Stmt       = (*ast.ReturnStmt)(nil)
+	_ ast.Stmt       = (*ast.DeclStmt)(nil)
+	_ ast.Stmt       = (*ast.IfStmt)(nil)
+	_ ast.Stmt       = (*ast.ForStmt)(nil)
+	_ ast.Stmt       = (*ast.WhileStmt)(nil)
+	_ ast.Stmt       = (*ast.SwitchStmt)(nil)
+	_ ast.Stmt       = (*ast.BlockStmt)(nil)
+
+	_ ast.Expr = (*ast.BadExpr)(nil)
+	_ ast.Expr = (*ast.Name)(nil)
+	_ ast.Expr = (*ast.BasicLit)(nil)
+	_ ast.Expr = (*ast.SliceLit)(nil)
+	_ ast.Expr = (*ast.CompositeLit)(nil)
+	_ ast.Expr = (*ast.KeyValueExpr)(nil)
+	_ ast.Expr = (*ast.Operation)(nil)
+	_ ast.Expr = (*ast.ParenExpr)(nil)
+	_ ast.Expr = (*ast.SliceType)(nil)
+	_ ast.Expr = (*ast.MapType)(nil)
+	_ ast.Expr = (*ast.PointerType)(nil)
+	_ ast.Expr = (*ast.DotsType)(nil)
+	_ ast.Expr = (*ast.StructType)(nil)
+	_ ast.Expr = (*ast.ListExpr)(nil)
+	_ ast.Expr = (*ast.SelectorExpr)(nil)
+	_ ast.Expr = (*ast.IndexExpr)(nil)
+	_ ast.Expr = (*ast.SliceExpr)(nil)
+	_ ast.Expr = (*ast.CallExpr)(nil)
+	_ ast.Expr = (*ast.Field)(nil)
+)
+
+func parseString(t *testing.T, src string) *ast.File {
+	t.Helper()
+	f, err := parser.Parse(position.NewFileBase("test.jindo"), strings.NewReader(src), func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestDiff(t *testing.T) {
+	const src = "space main\ntype T int\nfunc f(a T) T {\n\treturn a\n}\n"
+	a := parseString(t, src)
+	b := parseString(t, src+"\nfunc g() {}\n")
+
+	changes := ast.Diff(a, b)
+	if got, want := fmt.Sprint(changes), "[added func g]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if changes := ast.Diff(a, parseString(t, src)); len(changes) != 0 {
+		t.Errorf("got %v for identical files, want no changes", changes)
+	}
+}
+
+func TestFindCalls(t *testing.T) {
+	const src = `space main
+
+import "io"
+
+func f() {
+	foo(1)
+	if bar(foo(2)) {
+		x := io.foo()
+	}
+	fooBar()
+}`
+	f := parseString(t, src)
+
+	var got []string
+	for _, call := range ast.FindCalls(f, "foo") {
+		got = append(got, call.GetPos().String())
+	}
+	if want := "[test.jindo:6:5 test.jindo:7:12 test.jindo:8:14]"; fmt.Sprint(got) != want {
+		t.Errorf("got calls at %v, want %s", got, want)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	f := parseString(t, "space main\nfunc g() {}\nfunc f() {\n\tg()\n}\n")
+	decl := f.DeclList[0].(*ast.FuncDecl)
+	use := ast.FindCalls(f, "g")[0].Func.(*ast.Name)
+
+	resolved := ast.NewKey[ast.Decl]("resolved")
+	uses := ast.NewKey[int]("uses")
+
+	var a ast.Annotations
+	if _, ok := resolved.Get(&a, use); ok {
+		t.Errorf("got an annotation on an empty set")
+	}
+	resolved.Set(&a, use, decl)
+	uses.Set(&a, decl.Name, 1)
+
+	if got, ok := resolved.Get(&a, use); !ok || got != decl {
+		t.Errorf("got %v, %v, want the declaration of g", got, ok)
+	}
+	if _, ok := uses.Get(&a, use); ok {
+		t.Errorf("got a %s annotation on the use of g, want none", uses)
+	}
+	if _, ok := resolved.Get(&a, decl.Name); ok {
+		t.Errorf("got a %s annotation on the declared name, want none", resolved)
+	}
+	if _, ok := ast.NewKey[ast.Decl]("resolved").Get(&a, use); ok {
+		t.Errorf("got an annotation for a distinct key with the same name")
+	}
+
+	resolved.Delete(&a, use)
+	if _, ok := resolved.Get(&a, use); ok {
+		t.Errorf("got an annotation after Delete")
+	}
+	if n, ok := uses.Get(&a, decl.Name); !ok || n != 1 {
+		t.Errorf("got %d, %v, want 1, true", n, ok)
+	}
+}
+
+// children returns the nodes held in the exported fields of n,
+// directly or in slices, in field order.
+func children(n ast.Node) []ast.Node {
+	var list []ast.Node
+	var add func(v reflect.Value)
+	add = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				add(v.Index(i))
+			}
+		case reflect.Interface, reflect.Pointer:
+			if v.IsNil() {
+				return
+			}
+			if n, ok := v.Interface().(ast.Node); ok {
+				list = append(list, n)
+			}
+		}
+	}
+	v := reflect.ValueOf(n).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() {
+			add(v.Field(i))
+		}
+	}
+	return list
+}
+
+func TestWalk(t *testing.T) {
+	const src = `space main
+
+import io "io"
+
+type (
+	T struct {
+		a, b int
+		m    map[string]*T
+	}
+	L []int
+)
+
+const c int = 1
+
+var x, y = 1, 2
+
+oper (a T) add (b T) T {
+	return a
+}
+
+func (t *T) f(w io.Writer, xs ...int) int {
+	var z int
+	n := len(xs[1:2])
+	z += n
+	if n > 0 {
+		goto done
+	} else if !ok {
+		return -n
+	} else {
+		n = n + 1
+	}
+	for i := 0; i < n; i = i + 1 {
+		continue
+	}
+	while n > 0 {
+		break
+	}
+	switch n {
+	case 1, 2:
+		f(T{a: 1}, L{1, 2}, xs[0])
+	default:
+	}
+done:
+	return (n)
+}
+`
+	f := parseString(t, src)
+
+	count := make(map[ast.Node]int)
+	var stack []ast.Node
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			found := false
+			for _, c := range children(parent) {
+				found = found || c == n
+			}
+			if !found {
+				t.Errorf("%T at %v visited as a child of %T, which does not hold it", n, n.GetPos(), parent)
+			}
+		}
+		count[n]++
+		stack = append(stack, n)
+		return true
+	})
+
+	// Every node must be visited once for each parent holding it;
+	// fields declared together, as in a, b int, share their type.
+	want := make(map[ast.Node]int)
+	var hold func(n ast.Node)
+	hold = func(n ast.Node) {
+		if want[n]++; want[n] > 1 {
+			return
+		}
+		for _, c := range children(n) {
+			hold(c)
+		}
+	}
+	hold(f)
+	for n, c := range want {
+		if count[n] != c {
+			t.Errorf("%T at %v visited %d times, want %d", n, n.GetPos(), count[n], c)
+		}
+	}
+	if len(count) < 100 {
+		t.Errorf("got %d nodes, want a file exercising most node types", len(count))
+	}
+}
+
+func TestEnd(t *testing.T) {
+	const src = `space main
+
+func f(a int, b []int) int {
+	x := g(a, b[1:2])
+	if x > a+b*2 {
+		return (x)
+	}
+	return x
+}
+`
+	f := parseString(t, src)
+	fn := f.DeclList[0].(*ast.FuncDecl)
+	def := fn.Body.StmtList[0].(*ast.DefineStmt)
+	call := def.Rhs[0].(*ast.CallExpr)
+	ifs := fn.Body.StmtList[1].(*ast.IfStmt)
+
+	for _, test := range []struct {
+		n    ast.Node
+		want string
+	}{
+		{call, "g(a, b[1:2])"},
+		{call.ArgList[1], "b[1:2]"},
+		{ifs.Cond, "x > a+b*2"},
+		{ifs.Cond.(*ast.Operation).Y, "a+b*2"},
+		{ifs.Block, "{\n\t\treturn (x)\n\t}"},
+		{ifs.Block.StmtList[0], "return (x)"},
+		{fn.Body, src[strings.Index(src, "{") : len(src)-1]},
+		{fn.Param[1], "b []int"},
+		{def, "x := g(a, b[1:2])"},
+	} {
+		if got, _ := ast.SpanText([]byte(src), f.Pos.Base(), test.n); got != test.want {
+			t.Errorf("%T: got span %q, want %q", test.n, got, test.want)
+		}
+	}
+
+	// no node starts after its position or ends before it, or after the file
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil {
+			return true
+		}
+		start := ast.StartPos(n)
+		if pos := n.GetPos(); pos.IsKnown() && start.Cmp(pos) > 0 {
+			t.Errorf("%T at %v starts at %v", n, pos, start)
+		}
+		if n.End().Cmp(start) < 0 || n.End().Cmp(f.End()) > 0 {
+			t.Errorf("%T at %v ends at %v", n, start, n.End())
+		}
+		return true
+	})
+}
+
+func TestSpanText(t *testing.T) {
+	const src = "\ufeffspace main\n\nfunc f() int {\n\treturn 1\n}\n\n//line gen.jindo:10\nfunc g() {\n\th(\"a\", `b\nc`)\n}\n"
+	base := position.NewFileBase("test.jindo")
+	f, err := parser.Parse(base, strings.NewReader(src), func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{
+		"{\n\treturn 1\n}",
+		"{\n\th(\"a\", `b\nc`)\n}", // after a line directive
+	} {
+		body := f.DeclList[i].(*ast.FuncDecl).Body
+		if got, ok := ast.SpanText([]byte(src), base, body); !ok || got != want {
+			t.Errorf("body %d: got %q, %v, want %q, true", i, got, ok, want)
+		}
+	}
+	if got, ok := ast.SpanText([]byte(src), base, f.SpaceName); !ok || got != "main" {
+		t.Errorf("space name: got %q, %v, want \"main\", true", got, ok)
+	}
+
+	body := f.DeclList[0].(*ast.FuncDecl).Body
+	// lines ending with a lone carriage return or with \r\n
+	const crsrc = "space main\r\rfunc f() {\r\n\treturn\r}\n"
+	crbase := position.NewFileBase("test.jindo")
+	cf, err := parser.Parse(crbase, strings.NewReader(crsrc), func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := ast.SpanText([]byte(crsrc), crbase, cf.DeclList[0].(*ast.FuncDecl).Body); !ok || got != "{\r\n\treturn\r}" {
+		t.Errorf("body with carriage returns: got %q, %v, want %q, true", got, ok, "{\r\n\treturn\r}")
+	}
+
+	if _, ok := ast.SpanText([]byte(src), position.NewFileBase("other.jindo"), body); ok {
+		t.Error("got span in another file, want none")
+	}
+	if _, ok := ast.SpanText([]byte(src[:20]), base, body); ok {
+		t.Error("got span beyond the end of the source, want none")
+	}
+}
+
+func TestFold(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		kind token.LitKind
+		want string // "" means not folded
+	}{
+		{"2 + 3", token.IntLit, "5"},
+		{"2 + 3.0", token.FloatLit, "5.0"},
+		{"2.5 * 2", token.FloatLit, "5.0"},
+		{"7 / 2", token.IntLit, "3"},
+		{"7 / 2.0", token.FloatLit, "3.5"},
+		{"7 % 4", token.IntLit, "3"},
+		{"-(1_000 * 2)", token.IntLit, "-2000"},
+		{"1e3 - 1", token.FloatLit, "999.0"},
+		{"0x10 + 0.5", token.FloatLit, "16.5"},
+		{"x + 1", 0, ""},
+		{"1 / 0", 0, ""},
+		{"7 % 2.0", 0, ""},
+		{"1 == 1", 0, ""},
+	} {
+		list, err := parser.ParseExprList(test.src)
+		if err != nil {
+			t.Fatalf("%s: %v", test.src, err)
+		}
+		x := list[0]
+		got := ast.Fold(x)
+		if test.want == "" {
+			if got != x {
+				t.Errorf("%s: folded to %s, want unchanged", test.src, parser.String(got))
+			}
+			continue
+		}
+		lit, ok := got.(*ast.BasicLit)
+		if !ok || lit.Kind != test.kind || lit.Value != test.want {
+			t.Errorf("%s: got %#v, want %s of kind %d", test.src, got, test.want, test.kind)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	const src = `space main
+
+type T struct {
+	a, b int
+}
+
+var (
+	x = 1
+	y = 2
+)
+
+func f(t *T, xs ...int) int {
+	n := g(t.a, xs[0])
+	for i := 0; i < n; i = i + 1 {
+		n = n + i
+	}
+	switch n {
+	case 1, 2:
+		return n
+	}
+	return 0
+}
+`
+	print := func(n ast.Node) string {
+		var buf strings.Builder
+		if _, err := parser.Fprint(&buf, n, 0); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	f := parseString(t, src)
+	clone := ast.Clone(f).(*ast.File)
+	if got := print(clone); got != src {
+		t.Errorf("got clone\n%s\nwant\n%s", got, src)
+	}
+
+	orig := make(map[ast.Node]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		orig[n] = true
+		return true
+	})
+	ast.Inspect(clone, func(n ast.Node) bool {
+		if n != nil && orig[n] {
+			t.Errorf("%T at %v shared with the original", n, n.GetPos())
+		}
+		return true
+	})
+
+	// mutating a cloned function leaves the original unchanged
+	fn := f.DeclList[3].(*ast.FuncDecl)
+	want := print(fn)
+	c := ast.Clone(fn).(*ast.FuncDecl)
+	c.Name.Value = "h"
+	c.Param[0].Name.Value = "u"
+	call := c.Body.StmtList[0].(*ast.DefineStmt).Rhs[0].(*ast.CallExpr)
+	call.ArgList[0] = ast.NewName(call.Pos, "z")
+	call.ArgList = append(call.ArgList, ast.NewName(call.Pos, "w"))
+	c.Body.StmtList[1].(*ast.ForStmt).Body.StmtList = nil
+	c.Body.StmtList = c.Body.StmtList[:1]
+	if got := print(fn); got != want {
+		t.Errorf("original changed to\n%s\nwant\n%s", got, want)
+	}
+	if got := print(c); got == want {
+		t.Error("clone unchanged after mutation")
+	}
+}
+
+func TestDumpGolden(t *testing.T) {
+	const src = `space main
+
+import "io"
+
+type T struct {
+	a, b int
+}
+
+var (
+	x    []T
+	y, z = 1, "s"
+)
+
+func f(w io.Writer) int {
+	return g(x[0].a + 1)
+}
+`
+	got := ast.Sdump(parseString(t, src))
+
+	golden := filepath.Join("testdata", "dump.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	for _, test := range []struct {
+		a, b  string
+		equal bool
+	}{
+		{"f(x, 1)", "f(x, 1)", true},
+		{"f(x, 1)", "f( x,\t1 )", true},
+		{"a + b*c", "a + (b * c)", false}, // the parentheses are kept
+		{"f(x, 1)", "f(x)", false},
+		{"f(x, 1)", "f(x, 2)", false},
+		{"f(x, 1)", "g(x, 1)", false},
+		{"1", "1.0", false},
+		{"1", "'1'", false},
+		{"a + b", "a - b", false},
+		{"a < b", "b > a", false},
+		{"s[1:2]", "s[1:2:3]", false},
+		{"T{a: 1}", "T{a: 1}", true},
+		{"T{a: 1}", "T{b: 1}", false},
+	} {
+		a, err := parser.ParseExprList(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := parser.ParseExprList(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ast.Equal(a[0], b[0]); got != test.equal {
+			t.Errorf("Equal(%s, %s) = %v, want %v", test.a, test.b, got, test.equal)
+		}
+	}
+
+	// a file equals its reprinted and reparsed copy, though every
+	// position differs
+	f := parseString(t, "space main\nfunc f(a int) int { if a > 0 { return g(a, []int{1, 2}) }; return 0 }\n")
+	var buf strings.Builder
+	if _, err := parser.Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	g := parseString(t, buf.String())
+	if !ast.Equal(f, g) {
+		t.Errorf("reparsed file differs from the original:\n%s", buf.String())
+	}
+	if ast.Equal(f, nil) || !ast.Equal(nil, nil) {
+		t.Error("Equal with nil")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast
+
+// FindCalls returns the calls in f of a function named name, in source
+// order. A call matches if its Func is the Name name or a selector
+// x.name, such as a call qualified by an imported space.
+func FindCalls(f *File, name string) []*CallExpr {
+	var calls []*CallExpr
+	Inspect(f, func(n Node) bool {
+		if call, ok := n.(*CallExpr); ok {
+			switch fun := call.Func.(type) {
+			case *Name:
+				if fun.Value == name {
+					calls = append(calls, call)
+				}
+			case *SelectorExpr:
+				if fun.Sel != nil && fun.Sel.Value == name {
+					calls = append(calls, call)
+				}
+			}
+		}
+		return true
+	})
+	return calls
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast
+
+// CanonicalizeLoops rewrites, in place, every WhileStmt in f into the
+// equivalent condition-only ForStmt. Positions and loop bodies are
+// preserved, so the printer emits "for cond {...}" for every loop.
+func CanonicalizeLoops(f *File) {
+	// Inspect visits a statement before its children, so a loop
+	// replaced in its parent is then visited as the new ForStmt.
+	Inspect(f, func(n Node) bool {
+		switch n := n.(type) {
+		case *BlockStmt:
+			canonicalizeList(n.StmtList)
+		case *CaseClause:
+			canonicalizeList(n.Body)
+		case *LabeledStmt:
+			n.Stmt = canonicalizeStmt(n.Stmt)
+		}
+		return true
+	})
+}
+
+func canonicalizeList(list []Stmt) {
+	for i, s := range list {
+		list[i] = canonicalizeStmt(s)
+	}
+}
+
+// canonicalizeStmt returns the ForStmt equivalent to s if s is a
+// WhileStmt, and s otherwise.
+func canonicalizeStmt(s Stmt) Stmt {
+	w, ok := s.(*WhileStmt)
+	if !ok {
+		return s
+	}
+	f := new(ForStmt)
+	f.Pos = w.Pos
+	f.Cond = w.Cond
+	f.Body = w.Body
+	return f
+}
@@ -0,0 +1,420 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast
+
+import "fmt"
+
+// Clone returns a deep copy of the tree rooted at n, with the same
+// positions. The copy shares no nodes, slices or groups with n, but
+// preserves the sharing within n: fields declared in one list still
+// share their type, and declarations of one group their Group.
+func Clone(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	c := cloner{
+		nodes:  make(map[Node]Node),
+		groups: make(map[*Group]*Group),
+	}
+	return c.node(n)
+}
+
+type cloner struct {
+	nodes  map[Node]Node // clones of the nodes copied so far
+	groups map[*Group]*Group
+}
+
+func (c *cloner) node(n Node) Node {
+	if m, ok := c.nodes[n]; ok {
+		return m
+	}
+
+	var m Node
+	switch n := n.(type) {
+	// packages
+	case *File:
+		x := *n
+		x.Doc = append([]Comment(nil), n.Doc...)
+		x.SpaceName = c.name(n.SpaceName)
+		x.DeclList = c.declList(n.DeclList)
+		m = &x
+
+	// declarations
+	case *ImportDecl:
+		x := *n
+		x.Group = c.group(n.Group)
+		x.LocalName = c.name(n.LocalName)
+		if n.Path != nil {
+			x.Path = c.node(n.Path).(*BasicLit)
+		}
+		m = &x
+
+	case *OperDecl:
+		x := *n
+		x.Group = c.group(n.Group)
+		x.TypeL = c.field(n.TypeL)
+		x.TypeR = c.field(n.TypeR)
+		x.Return = c.expr(n.Return)
+		x.Body = c.block(n.Body)
+		m = &x
+
+	case *TypeDecl:
+		x := *n
+		x.Group = c.group(n.Group)
+		x.Name = c.name(n.Name)
+		x.Type = c.expr(n.Type)
+		m = &x
+
+	case *ConstDecl:
+		x := *n
+		x.Group = c.group(n.Group)
+		x.NameList = c.name(n.NameList)
+		x.Type = c.expr(n.Type)
+		x.Values = c.expr(n.Values)
+		m = &x
+
+	case *VarDecl:
+		x := *n
+		x.Group = c.group(n.Group)
+		x.NameList = c.nameList(n.NameList)
+		x.Type = c.expr(n.Type)
+		x.Values = c.expr(n.Values)
+		m = &x
+
+	case *FuncDecl:
+		x := *n
+		x.Group = c.group(n.Group)
+		x.Recv = c.field(n.Recv)
+		x.Param = c.fieldList(n.Param)
+		x.Name = c.name(n.Name)
+		x.Return = c.expr(n.Return)
+		x.Body = c.block(n.Body)
+		m = &x
+
+	// expressions
+	case *BadExpr:
+		x := *n
+		m = &x
+
+	case *Name:
+		x := *n
+		m = &x
+
+	case *BasicLit:
+		x := *n
+		m = &x
+
+	case *SliceLit:
+		x := *n
+		x.ElemType = c.expr(n.ElemType)
+		x.Elems = c.exprList(n.Elems)
+		m = &x
+
+	case *CompositeLit:
+		x := *n
+		x.Type = c.expr(n.Type)
+		x.ElemList = c.exprList(n.ElemList)
+		m = &x
+
+	case *KeyValueExpr:
+		x := *n
+		x.Key = c.expr(n.Key)
+		x.Value = c.expr(n.Value)
+		m = &x
+
+	case *Operation:
+		x := *n
+		x.X = c.expr(n.X)
+		x.Y = c.expr(n.Y)
+		m = &x
+
+	case *ParenExpr:
+		x := *n
+		x.X = c.expr(n.X)
+		m = &x
+
+	case *SliceType:
+		x := *n
+		x.Elem = c.expr(n.Elem)
+		m = &x
+
+	case *ListExpr:
+		x := *n
+		x.ElemList = c.exprList(n.ElemList)
+		m = &x
+
+	case *MapType:
+		x := *n
+		x.Key = c.expr(n.Key)
+		x.Value = c.expr(n.Value)
+		m = &x
+
+	case *PointerType:
+		x := *n
+		x.Elem = c.expr(n.Elem)
+		m = &x
+
+	case *DotsType:
+		x := *n
+		x.Elem = c.expr(n.Elem)
+		m = &x
+
+	case *StructType:
+		x := *n
+		x.FieldList = c.fieldList(n.FieldList)
+		m = &x
+
+	case *SelectorExpr:
+		x := *n
+		x.X = c.expr(n.X)
+		x.Sel = c.name(n.Sel)
+		m = &x
+
+	case *IndexExpr:
+		x := *n
+		x.X = c.expr(n.X)
+		x.Index = c.expr(n.Index)
+		m = &x
+
+	case *SliceExpr:
+		x := *n
+		x.X = c.expr(n.X)
+		x.Low = c.expr(n.Low)
+		x.High = c.expr(n.High)
+		x.Max = c.expr(n.Max)
+		m = &x
+
+	case *CallExpr:
+		x := *n
+		x.Func = c.expr(n.Func)
+		x.ArgList = c.exprList(n.ArgList)
+		m = &x
+
+	case *Field:
+		x := *n
+		x.Name = c.name(n.Name)
+		x.Type = c.expr(n.Type)
+		m = &x
+
+	// statements
+	case *EmptyStmt:
+		x := *n
+		m = &x
+
+	case *ContinueStmt:
+		x := *n
+		x.Label = c.name(n.Label)
+		m = &x
+
+	case *BreakStmt:
+		x := *n
+		x.Label = c.name(n.Label)
+		m = &x
+
+	case *GotoStmt:
+		x := *n
+		x.Label = c.name(n.Label)
+		m = &x
+
+	case *LabeledStmt:
+		x := *n
+		x.Label = c.name(n.Label)
+		x.Stmt = c.stmt(n.Stmt)
+		m = &x
+
+	case *ExprStmt:
+		x := *n
+		x.X = c.expr(n.X)
+		m = &x
+
+	case *IncDecStmt:
+		x := *n
+		x.X = c.expr(n.X)
+		m = &x
+
+	case *ReturnStmt:
+		x := *n
+		x.Result = c.expr(n.Result)
+		m = &x
+
+	case *DeclStmt:
+		x := *n
+		x.DeclList = c.declList(n.DeclList)
+		m = &x
+
+	case *DefineStmt:
+		x := *n
+		x.Lhs = c.exprList(n.Lhs)
+		x.Rhs = c.exprList(n.Rhs)
+		m = &x
+
+	case *AssignStmt:
+		x := *n
+		x.Lhs = c.exprList(n.Lhs)
+		x.Rhs = c.exprList(n.Rhs)
+		m = &x
+
+	case *BlockStmt:
+		x := *n
+		x.StmtList = c.stmtList(n.StmtList)
+		m = &x
+
+	case *IfStmt:
+		x := *n
+		x.Cond = c.expr(n.Cond)
+		x.Block = c.block(n.Block)
+		x.Else = c.stmt(n.Else)
+		m = &x
+
+	case *ForStmt:
+		x := *n
+		x.Init = c.simpleStmt(n.Init)
+		x.Cond = c.expr(n.Cond)
+		x.Post = c.simpleStmt(n.Post)
+		x.Body = c.block(n.Body)
+		m = &x
+
+	case *WhileStmt:
+		x := *n
+		x.Cond = c.expr(n.Cond)
+		x.Body = c.block(n.Body)
+		m = &x
+
+	case *SwitchStmt:
+		x := *n
+		x.Init = c.simpleStmt(n.Init)
+		x.Tag = c.expr(n.Tag)
+		if n.Body != nil {
+			x.Body = make([]*CaseClause, len(n.Body))
+			for i, cc := range n.Body {
+				x.Body[i] = c.node(cc).(*CaseClause)
+			}
+		}
+		m = &x
+
+	case *CaseClause:
+		x := *n
+		x.CaseList = c.exprList(n.CaseList)
+		x.Body = c.stmtList(n.Body)
+		m = &x
+
+	default:
+		panic(fmt.Sprintf("internal error: unknown node type %T", n))
+	}
+
+	c.nodes[n] = m
+	return m
+}
+
+func (c *cloner) group(g *Group) *Group {
+	if g == nil {
+		return nil
+	}
+	if h, ok := c.groups[g]; ok {
+		return h
+	}
+	h := new(Group)
+	c.groups[g] = h
+	return h
+}
+
+func (c *cloner) expr(x Expr) Expr {
+	if x == nil {
+		return nil
+	}
+	return c.node(x).(Expr)
+}
+
+func (c *cloner) stmt(s Stmt) Stmt {
+	if s == nil {
+		return nil
+	}
+	return c.node(s).(Stmt)
+}
+
+func (c *cloner) simpleStmt(s SimpleStmt) SimpleStmt {
+	if s == nil {
+		return nil
+	}
+	return c.node(s).(SimpleStmt)
+}
+
+func (c *cloner) name(n *Name) *Name {
+	if n == nil {
+		return nil
+	}
+	return c.node(n).(*Name)
+}
+
+func (c *cloner) field(f *Field) *Field {
+	if f == nil {
+		return nil
+	}
+	return c.node(f).(*Field)
+}
+
+func (c *cloner) block(b *BlockStmt) *BlockStmt {
+	if b == nil {
+		return nil
+	}
+	return c.node(b).(*BlockStmt)
+}
+
+func (c *cloner) declList(list []Decl) []Decl {
+	if list == nil {
+		return nil
+	}
+	l := make([]Decl, len(list))
+	for i, d := range list {
+		l[i] = c.node(d).(Decl)
+	}
+	return l
+}
+
+func (c *cloner) exprList(list []Expr) []Expr {
+	if list == nil {
+		return nil
+	}
+	l := make([]Expr, len(list))
+	for i, x := range list {
+		l[i] = c.expr(x)
+	}
+	return l
+}
+
+func (c *cloner) stmtList(list []Stmt) []Stmt {
+	if list == nil {
+		return nil
+	}
+	l := make([]Stmt, len(list))
+	for i, s := range list {
+		l[i] = c.stmt(s)
+	}
+	return l
+}
+
+func (c *cloner) nameList(list []*Name) []*Name {
+	if list == nil {
+		return nil
+	}
+	l := make([]*Name, len(list))
+	for i, n := range list {
+		l[i] = c.name(n)
+	}
+	return l
+}
+
+func (c *cloner) fieldList(list []*Field) []*Field {
+	if list == nil {
+		return nil
+	}
+	l := make([]*Field, len(list))
+	for i, f := range list {
+		l[i] = c.field(f)
+	}
+	return l
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/position"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind describes how a declaration differs between two files.
+type ChangeKind uint8
+
+const (
+	Added   ChangeKind = iota // declared in the new file only
+	Removed                   // declared in the old file only
+	Changed                   // declared in both, but differently
+)
+
+var changeKindString = [...]string{
+	Added:   "added",
+	Removed: "removed",
+	Changed: "changed",
+}
+
+func (k ChangeKind) String() string { return changeKindString[k] }
+
+// A Change is a difference between the declarations of two files.
+type Change struct {
+	Kind ChangeKind
+	Name string // declaration key, see DeclKey
+	Old  Decl   // nil if Kind == Added
+	New  Decl   // nil if Kind == Removed
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s", c.Kind, c.Name)
+}
+
+// Diff returns the structural differences between the top-level
+// declarations of a and b, matched by DeclKey. Positions are ignored.
+// Removed and changed declarations are listed in the order of a,
+// followed by added declarations in the order of b.
+func Diff(a, b *File) []Change {
+	bdecls := make(map[string]Decl)
+	for _, d := range b.DeclList {
+		bdecls[DeclKey(d)] = d
+	}
+
+	var list []Change
+	seen := make(map[string]bool)
+	for _, d := range a.DeclList {
+		key := DeclKey(d)
+		seen[key] = true
+		switch nd, ok := bdecls[key]; {
+		case !ok:
+			list = append(list, Change{Removed, key, d, nil})
+		case !equalNodes(reflect.ValueOf(d), reflect.ValueOf(nd)):
+			list = append(list, Change{Changed, key, d, nd})
+		}
+	}
+	for _, d := range b.DeclList {
+		if key := DeclKey(d); !seen[key] {
+			seen[key] = true
+			list = append(list, Change{Added, key, nil, d})
+		}
+	}
+	return list
+}
+
+// DeclKey returns the name identifying d among the declarations of a
+// space, such as "func f", "type T", or `import "path"`.
+func DeclKey(d Decl) string {
+	switch d := d.(type) {
+	case *ImportDecl:
+		key := "import"
+		if d.LocalName != nil {
+			key += " " + d.LocalName.Value
+		}
+		if d.Path != nil {
+			key += " " + d.Path.Value
+		}
+		return key
+	case *TypeDecl:
+		return "type " + d.Name.Value
+	case *ConstDecl:
+		return "const " + d.NameList.Value
+	case *VarDecl:
+		names := make([]string, len(d.NameList))
+		for i, n := range d.NameList {
+			names[i] = n.Value
+		}
+		return "var " + strings.Join(names, ", ")
+	case *FuncDecl:
+		if d.Recv != nil {
+			return "func (" + typeKey(d.Recv.Type) + ") " + d.Name.Value
+		}
+		return "func " + d.Name.Value
+	case *OperDecl:
+		var l, r Expr
+		if d.TypeL != nil {
+			l = d.TypeL.Type
+		}
+		if d.TypeR != nil {
+			r = d.TypeR.Type
+		}
+		return "oper (" + typeKey(l) + ") " + strconv.Itoa(int(d.Oper)) + " (" + typeKey(r) + ")"
+	}
+	return fmt.Sprintf("%T", d)
+}
+
+func typeKey(x Expr) string {
+	switch x := x.(type) {
+	case *Name:
+		return x.Value
+	case *SelectorExpr:
+		return typeKey(x.X) + "." + x.Sel.Value
+	case *SliceType:
+		return "[]" + typeKey(x.Elem)
+	case *ParenExpr:
+		return typeKey(x.X)
+	}
+	return "?"
+}
+
+// Equal reports whether a and b are structurally equal syntax trees:
+// nodes of the same types, with the same names, literal values and
+// kinds, operators and children, in the same order. Positions are
+// ignored, so a tree equals its reprinted and reparsed copy.
+func Equal(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return equalNodes(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+var posType = reflect.TypeOf(position.Pos{})
+
+// equalNodes reports whether x and y hold structurally equal
+// syntax trees. Positions and unexported fields are ignored.
+func equalNodes(x, y reflect.Value) bool {
+	if x.Kind() != y.Kind() || x.Type() != y.Type() {
+		return false
+	}
+	switch x.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if x.IsNil() || y.IsNil() {
+			return x.IsNil() == y.IsNil()
+		}
+		return equalNodes(x.Elem(), y.Elem())
+	case reflect.Slice:
+		if x.Len() != y.Len() {
+			return false
+		}
+		for i := 0; i < x.Len(); i++ {
+			if !equalNodes(x.Index(i), y.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if x.Type() == posType {
+			return true
+		}
+		for i := 0; i < x.NumField(); i++ {
+			if x.Type().Field(i).IsExported() && !equalNodes(x.Field(i), y.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return x.Interface() == y.Interface()
+	}
+}
@@ -9,18 +9,26 @@ package ast
 import (
 	"fmt"
 	"io"
+	"jindo/pkg/jindo/position"
 	"reflect"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
-// Fdump dumps the structure of the syntax tree rooted at n to w.
-// It is intended for debugging purposes; no specific output format
-// is guaranteed.
+// Fdump dumps the structure of the syntax tree rooted at n to w, one
+// numbered line per node, field or slice element, indented by depth.
+// Each node starts with its position, followed by its exported fields
+// in declaration order. A nil pointer, interface or slice prints as nil,
+// and an empty slice as "{}" after its type and length. A node or group
+// reached a second time, as a type shared by several fields, prints as
+// a reference to the line or number it was first printed with. The
+// output is deterministic, and tests rely on it.
 func Fdump(w io.Writer, n Node) (err error) {
 	p := dumper{
 		output: w,
 		ptrmap: make(map[Node]int),
+		groups: make(map[*Group]int),
 		last:   '\n', // force printing of line number on first line
 	}
 
@@ -40,12 +48,20 @@ func Fdump(w io.Writer, n Node) (err error) {
 	return
 }
 
+// Sdump is like Fdump but returns the dump as a string.
+func Sdump(n Node) string {
+	var buf strings.Builder
+	Fdump(&buf, n) // a strings.Builder never fails
+	return buf.String()
+}
+
 type dumper struct {
 	output io.Writer
-	ptrmap map[Node]int // node -> dump line number
-	indent int          // current indentation level
-	last   byte         // last byte processed by Write
-	line   int          // current line number
+	ptrmap map[Node]int   // node -> dump line number
+	groups map[*Group]int // group -> group number, from 1
+	indent int            // current indentation level
+	last   byte           // last byte processed by Write
+	line   int            // current line number
 }
 
 var indentBytes = []byte(".  ")
@@ -100,10 +116,9 @@ func (p *dumper) printf(format string, args ...interface{}) {
 // dump prints the contents of x.
 // If x is the reflect.Value of a struct s, where &s
 // implements ast.Node, then &s should be passed for n -
-// this permits printing of the unexported span and
-// comments fields of the embedded isast.Node field by
-// calling the Span() and Comment() instead of using
-// reflection.
+// this permits printing of the position held in the
+// unexported embedded node field by calling GetPos()
+// instead of using reflection.
 func (p *dumper) dump(x reflect.Value, n Node) {
 	switch x.Kind() {
 	case reflect.Interface:
@@ -121,7 +136,16 @@ func (p *dumper) dump(x reflect.Value, n Node) {
 
 		// special cases for identifiers w/o attached comments (common case)
 		if x, ok := x.Interface().(*Name); ok {
-			p.printf("%s @ %v", x.Value, x.GetPos())
+			p.printf("%s @ %s", x.Value, dumpPos(x.GetPos()))
+			return
+		}
+
+		// groups have no fields, only an identity
+		if g, ok := x.Interface().(*Group); ok {
+			if _, exists := p.groups[g]; !exists {
+				p.groups[g] = len(p.groups) + 1
+			}
+			p.printf("*ast.Group #%d", p.groups[g])
 			return
 		}
 
@@ -160,24 +184,19 @@ func (p *dumper) dump(x reflect.Value, n Node) {
 	case reflect.Struct:
 		typ := x.Type()
 
-		// if span, ok := x.Interface().(lexical.Span); ok {
-		// 	p.printf("%s", &span)
-		// 	return
-		// }
+		if pos, ok := x.Interface().(position.Pos); ok {
+			p.printf("%s", dumpPos(pos))
+			return
+		}
 
 		p.printf("%s {", typ)
 		p.indent++
 
 		first := true
 		if n != nil {
-			p.printf("\n")
+			// the position is held in an unexported embedded field
+			p.printf("\nPos: %s\n", dumpPos(n.GetPos()))
 			first = false
-			// p.printf("Span: %s\n", n.Span())
-			// if c := *n.Comments(); c != nil {
-			// 	p.printf("Comments: ")
-			// 	p.dump(reflect.ValueOf(c), nil) // a Comment is not a ast.Node
-			// 	p.printf("\n")
-			// }
 		}
 
 		for i, n := 0, typ.NumField(); i < n; i++ {
@@ -208,6 +227,16 @@ func (p *dumper) dump(x reflect.Value, n Node) {
 	}
 }
 
+// dumpPos formats pos as "line:col", or "-" if it is unknown. The file
+// name is left out: it is the same throughout a dump but for line
+// directives.
+func dumpPos(pos position.Pos) string {
+	if !pos.IsKnown() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", pos.Line(), pos.Col())
+}
+
 func isExported(name string) bool {
 	ch, _ := utf8.DecodeRuneInString(name)
 	return unicode.IsUpper(ch)
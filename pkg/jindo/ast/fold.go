@@ -0,0 +1,116 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast
+
+import (
+	"go/constant"
+	gotoken "go/token"
+	"jindo/pkg/jindo/token"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Fold returns the constant expression x folded into a single integer
+// or floating-point literal positioned at StartPos(x), or x itself if
+// x is not made only of such literals, parentheses and the arithmetic
+// operators + - * / and %. The literal is an IntLit if every operand is
+// an integer, in which case division truncates, and a FloatLit as soon
+// as one operand is floating-point.
+func Fold(x Expr) Expr {
+	v, kind, ok := fold(x)
+	if !ok {
+		return x
+	}
+	lit := new(BasicLit)
+	lit.Pos = StartPos(x)
+	lit.Kind = kind
+	if kind == token.IntLit {
+		lit.Value = v.ExactString()
+	} else {
+		f, _ := constant.Float64Val(v)
+		if math.IsInf(f, 0) {
+			return x
+		}
+		lit.Value = strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(lit.Value, ".e") {
+			lit.Value += ".0" // keep it a floating-point literal
+		}
+	}
+	return lit
+}
+
+// fold returns the value of x and whether it is an integer or a
+// floating-point constant, or false if x cannot be folded.
+func fold(x Expr) (constant.Value, token.LitKind, bool) {
+	switch x := x.(type) {
+	case *BasicLit:
+		lit := strings.ReplaceAll(x.Value, "_", "")
+		switch {
+		case x.Bad:
+		case x.Kind == token.IntLit:
+			return constant.MakeFromLiteral(lit, gotoken.INT, 0), token.IntLit, true
+		case x.Kind == token.FloatLit:
+			return constant.MakeFromLiteral(lit, gotoken.FLOAT, 0), token.FloatLit, true
+		}
+
+	case *ParenExpr:
+		return fold(x.X)
+
+	case *Operation:
+		v, kind, ok := fold(x.X)
+		if !ok {
+			break
+		}
+		if x.Y == nil {
+			switch x.Op {
+			case token.Add:
+				return v, kind, true
+			case token.Sub:
+				return constant.UnaryOp(gotoken.SUB, v, 0), kind, true
+			}
+			break
+		}
+
+		w, wkind, ok := fold(x.Y)
+		if !ok {
+			break
+		}
+		if wkind == token.FloatLit {
+			kind = token.FloatLit
+		}
+		if kind == token.FloatLit {
+			v, w = constant.ToFloat(v), constant.ToFloat(w)
+		}
+		var op gotoken.Token
+		switch x.Op {
+		case token.Add:
+			op = gotoken.ADD
+		case token.Sub:
+			op = gotoken.SUB
+		case token.Mul:
+			op = gotoken.MUL
+		case token.Div:
+			if constant.Sign(w) == 0 {
+				return nil, 0, false
+			}
+			op = gotoken.QUO
+			if kind == token.IntLit {
+				op = gotoken.QUO_ASSIGN // truncating integer division
+			}
+		case token.Rem:
+			if kind != token.IntLit || constant.Sign(w) == 0 {
+				return nil, 0, false
+			}
+			op = gotoken.REM
+		default:
+			return nil, 0, false
+		}
+		return constant.BinaryOp(v, op, w), kind, true
+	}
+	return nil, 0, false
+}
@@ -0,0 +1,345 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast
+
+import (
+	"bytes"
+	"jindo/pkg/jindo/position"
+	"strings"
+)
+
+// StartPos returns the position of the first character of n. This is
+// n.GetPos() for most nodes, but binary operations, calls, selectors,
+// index and slice expressions, keyed elements and assignments are
+// positioned at their operator, after their leftmost operand, and the
+// fields of a signature have no position of their own.
+func StartPos(n Node) position.Pos {
+	for {
+		switch m := n.(type) {
+		case *Operation:
+			if m.Y == nil {
+				return m.Pos
+			}
+			n = m.X
+		case *CallExpr:
+			n = m.Func
+		case *SelectorExpr:
+			n = m.X
+		case *IndexExpr:
+			n = m.X
+		case *SliceExpr:
+			n = m.X
+		case *CompositeLit:
+			if m.Type == nil {
+				return m.Pos
+			}
+			n = m.Type
+		case *KeyValueExpr:
+			n = m.Key
+		case *ListExpr:
+			if m.Rparen.IsKnown() || len(m.ElemList) == 0 {
+				return m.Pos
+			}
+			n = m.ElemList[0]
+		case *Field:
+			if m.Name == nil {
+				n = m.Type
+			} else {
+				n = m.Name
+			}
+		case *ExprStmt:
+			n = m.X
+		case *IncDecStmt:
+			n = m.X
+		case *LabeledStmt:
+			n = m.Label
+		case *DefineStmt:
+			if len(m.Lhs) == 0 {
+				return m.Pos
+			}
+			n = m.Lhs[0]
+		case *AssignStmt:
+			if len(m.Lhs) == 0 {
+				return m.Pos
+			}
+			n = m.Lhs[0]
+		default:
+			return n.GetPos()
+		}
+	}
+}
+
+// SpanText returns the source text of n, from StartPos(n) up to n.End(),
+// given the source src of the file whose positions have base base. It
+// reports false if the span of n is not known, lies in another file, or
+// does not fit in src.
+func SpanText(src []byte, base *position.PosBase, n Node) (string, bool) {
+	start, end := StartPos(n), n.End()
+	if !start.IsKnown() || !end.IsKnown() || fileBase(start.Base()) != base || fileBase(end.Base()) != base {
+		return "", false
+	}
+	i, ok := offset(src, start)
+	j, ok2 := offset(src, end)
+	if !ok || !ok2 || i > j {
+		return "", false
+	}
+	return string(src[i:j]), true
+}
+
+// fileBase returns the base of the file holding the positions with base
+// b, which differs from b after a line directive.
+func fileBase(b *position.PosBase) *position.PosBase {
+	for b != nil && b.Pos().Base() != b {
+		b = b.Pos().Base()
+	}
+	return b
+}
+
+// offset returns the byte offset of pos in src. A leading byte order
+// mark does not count towards the column, as in the scanner.
+func offset(src []byte, pos position.Pos) (int, bool) {
+	off := 0
+	for line := uint(1); line < pos.Line(); line++ {
+		// lines end with "\n", "\r\n" or a lone "\r", as in the scanner
+		i := bytes.IndexAny(src[off:], "\r\n")
+		if i < 0 {
+			return 0, false
+		}
+		off += i + 1
+		if src[off-1] == '\r' && off < len(src) && src[off] == '\n' {
+			off++
+		}
+	}
+	if pos.Line() == 1 && bytes.HasPrefix(src, []byte("\ufeff")) {
+		off += len("\ufeff")
+	}
+	off += int(pos.Col()) - position.Colbase
+	if off > len(src) {
+		return 0, false
+	}
+	return off, true
+}
+
+// after returns the position immediately after text, which starts at
+// pos. Columns count bytes, as the scanner does.
+func after(pos position.Pos, text string) position.Pos {
+	if !pos.IsKnown() {
+		return pos
+	}
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		return position.MakePos(pos.Base(), pos.Line()+uint(strings.Count(text, "\n")), uint(len(text)-i))
+	}
+	return position.MakePos(pos.Base(), pos.Line(), pos.Col()+uint(len(text)))
+}
+
+// File
+
+func (n *File) End() position.Pos { return n.EOF }
+
+// Declarations
+
+func (n *ImportDecl) End() position.Pos {
+	switch {
+	case n.Path != nil:
+		return n.Path.End()
+	case n.LocalName != nil:
+		return n.LocalName.End()
+	}
+	return n.Pos
+}
+
+func (n *OperDecl) End() position.Pos {
+	switch {
+	case n.Body != nil:
+		return n.Body.End()
+	case n.Return != nil:
+		return n.Return.End()
+	}
+	return n.Pos
+}
+
+func (n *TypeDecl) End() position.Pos {
+	if n.Type != nil {
+		return n.Type.End()
+	}
+	return n.Name.End()
+}
+
+func (n *ConstDecl) End() position.Pos {
+	switch {
+	case n.Values != nil:
+		return n.Values.End()
+	case n.Type != nil:
+		return n.Type.End()
+	}
+	return n.NameList.End()
+}
+
+func (n *VarDecl) End() position.Pos {
+	switch {
+	case n.Values != nil:
+		return n.Values.End()
+	case n.Type != nil:
+		return n.Type.End()
+	}
+	return n.NameList[len(n.NameList)-1].End()
+}
+
+func (n *FuncDecl) End() position.Pos {
+	switch {
+	case n.Body != nil:
+		return n.Body.End()
+	case n.Return != nil:
+		return n.Return.End()
+	case n.Rparen.IsKnown():
+		return after(n.Rparen, ")")
+	}
+	return n.Name.End()
+}
+
+// Statements
+
+func (n *ExprStmt) End() position.Pos   { return n.X.End() }
+func (n *EmptyStmt) End() position.Pos  { return n.Pos }
+func (n *IncDecStmt) End() position.Pos { return after(n.X.End(), "++") } // or "--"
+
+func (n *ContinueStmt) End() position.Pos {
+	if n.Label != nil {
+		return n.Label.End()
+	}
+	return after(n.Pos, "continue")
+}
+
+func (n *BreakStmt) End() position.Pos {
+	if n.Label != nil {
+		return n.Label.End()
+	}
+	return after(n.Pos, "break")
+}
+
+func (n *GotoStmt) End() position.Pos {
+	if n.Label != nil {
+		return n.Label.End()
+	}
+	return after(n.Pos, "goto")
+}
+
+func (n *LabeledStmt) End() position.Pos {
+	if n.Stmt != nil {
+		return n.Stmt.End()
+	}
+	return after(n.Label.End(), ":")
+}
+
+func (n *ReturnStmt) End() position.Pos {
+	if n.Result != nil {
+		return n.Result.End()
+	}
+	return after(n.Pos, "return")
+}
+
+func (n *DeclStmt) End() position.Pos {
+	switch {
+	case n.Rparen.IsKnown():
+		return after(n.Rparen, ")")
+	case len(n.DeclList) > 0:
+		return n.DeclList[len(n.DeclList)-1].End()
+	}
+	return n.Pos
+}
+
+func (n *DefineStmt) End() position.Pos {
+	if len(n.Rhs) > 0 {
+		return n.Rhs[len(n.Rhs)-1].End()
+	}
+	return n.Pos
+}
+
+func (n *AssignStmt) End() position.Pos {
+	if len(n.Rhs) > 0 {
+		return n.Rhs[len(n.Rhs)-1].End()
+	}
+	return n.Pos
+}
+
+func (n *IfStmt) End() position.Pos {
+	if n.Else != nil {
+		return n.Else.End()
+	}
+	return n.Block.End()
+}
+
+func (n *ForStmt) End() position.Pos    { return n.Body.End() }
+func (n *WhileStmt) End() position.Pos  { return n.Body.End() }
+func (n *SwitchStmt) End() position.Pos { return after(n.Rbrace, "}") }
+
+func (n *CaseClause) End() position.Pos {
+	if len(n.Body) > 0 {
+		return n.Body[len(n.Body)-1].End()
+	}
+	return after(n.Colon, ":")
+}
+
+func (n *BlockStmt) End() position.Pos { return after(n.Rbrace, "}") }
+
+// Expressions
+
+func (n *BadExpr) End() position.Pos      { return n.Pos }
+func (n *Name) End() position.Pos         { return after(n.Pos, n.Value) }
+func (n *BasicLit) End() position.Pos     { return after(n.Pos, n.Value) }
+func (n *SliceLit) End() position.Pos     { return after(n.Rbrace, "}") }
+func (n *CompositeLit) End() position.Pos { return after(n.Rbrace, "}") }
+func (n *KeyValueExpr) End() position.Pos { return n.Value.End() }
+
+func (n *Operation) End() position.Pos {
+	if n.Y != nil {
+		return n.Y.End()
+	}
+	return n.X.End()
+}
+
+func (n *ParenExpr) End() position.Pos { return after(n.Rparen, ")") }
+
+func (n *SliceType) End() position.Pos {
+	if n.Elem != nil {
+		return n.Elem.End()
+	}
+	return after(n.Pos, "[]")
+}
+
+func (n *MapType) End() position.Pos     { return n.Value.End() }
+func (n *PointerType) End() position.Pos { return n.Elem.End() }
+func (n *DotsType) End() position.Pos    { return n.Elem.End() }
+func (n *StructType) End() position.Pos  { return after(n.Rbrace, "}") }
+
+func (n *ListExpr) End() position.Pos {
+	switch {
+	case n.Rparen.IsKnown():
+		return after(n.Rparen, ")")
+	case len(n.ElemList) > 0:
+		return n.ElemList[len(n.ElemList)-1].End()
+	}
+	return n.Pos
+}
+
+func (n *SelectorExpr) End() position.Pos {
+	if n.Sel != nil {
+		return n.Sel.End()
+	}
+	return n.X.End()
+}
+
+func (n *IndexExpr) End() position.Pos { return after(n.Rbrack, "]") }
+func (n *SliceExpr) End() position.Pos { return after(n.Rbrack, "]") }
+func (n *CallExpr) End() position.Pos  { return after(n.Rparen, ")") }
+
+func (n *Field) End() position.Pos {
+	if n.Type != nil {
+		return n.Type.End()
+	}
+	return n.Name.End()
+}
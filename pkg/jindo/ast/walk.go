@@ -0,0 +1,333 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package ast
+
+import "fmt"
+
+// Walk traverses an AST in pre-order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call
+// of w.Visit(nil).
+func Walk(root Node, v Visitor) {
+	walker{v}.node(root)
+}
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Inspect traverses an AST in pre-order: It starts by calling f(root);
+// root must not be nil. If f returns true, Inspect invokes f recursively
+// for each of the non-nil children of root, followed by a call of f(nil).
+func Inspect(root Node, f func(Node) bool) {
+	Walk(root, inspector(f))
+}
+
+type inspector func(Node) bool
+
+func (v inspector) Visit(node Node) Visitor {
+	if v(node) {
+		return v
+	}
+	return nil
+}
+
+type walker struct {
+	v Visitor
+}
+
+func (w walker) node(n Node) {
+	if n == nil {
+		panic("nil node")
+	}
+
+	w.v = w.v.Visit(n)
+	if w.v == nil {
+		return
+	}
+
+	switch n := n.(type) {
+	// packages
+	case *File:
+		if n.SpaceName != nil {
+			w.node(n.SpaceName)
+		}
+		w.declList(n.DeclList)
+
+	// declarations
+	case *ImportDecl:
+		if n.LocalName != nil {
+			w.node(n.LocalName)
+		}
+		if n.Path != nil {
+			w.node(n.Path)
+		}
+
+	case *OperDecl:
+		if n.TypeL != nil {
+			w.node(n.TypeL)
+		}
+		if n.TypeR != nil {
+			w.node(n.TypeR)
+		}
+		if n.Return != nil {
+			w.node(n.Return)
+		}
+		if n.Body != nil {
+			w.node(n.Body)
+		}
+
+	case *TypeDecl:
+		w.node(n.Name)
+		w.node(n.Type)
+
+	case *ConstDecl:
+		w.node(n.NameList)
+		if n.Type != nil {
+			w.node(n.Type)
+		}
+		if n.Values != nil {
+			w.node(n.Values)
+		}
+
+	case *VarDecl:
+		w.nameList(n.NameList)
+		if n.Type != nil {
+			w.node(n.Type)
+		}
+		if n.Values != nil {
+			w.node(n.Values)
+		}
+
+	case *FuncDecl:
+		if n.Recv != nil {
+			w.node(n.Recv)
+		}
+		w.node(n.Name)
+		w.fieldList(n.Param)
+		if n.Return != nil {
+			w.node(n.Return)
+		}
+		if n.Body != nil {
+			w.node(n.Body)
+		}
+
+	// expressions
+	case *BadExpr: // nothing to do
+	case *Name: // nothing to do
+	case *BasicLit: // nothing to do
+
+	case *SliceLit:
+		if n.ElemType != nil {
+			w.node(n.ElemType)
+		}
+		w.exprList(n.Elems)
+
+	case *CompositeLit:
+		w.node(n.Type)
+		w.exprList(n.ElemList)
+
+	case *KeyValueExpr:
+		w.node(n.Key)
+		w.node(n.Value)
+
+	case *Operation:
+		w.node(n.X)
+		if n.Y != nil {
+			w.node(n.Y)
+		}
+
+	case *ParenExpr:
+		w.node(n.X)
+
+	case *SliceType:
+		if n.Elem != nil {
+			w.node(n.Elem)
+		}
+
+	case *ListExpr:
+		w.exprList(n.ElemList)
+
+	case *MapType:
+		w.node(n.Key)
+		w.node(n.Value)
+
+	case *PointerType:
+		w.node(n.Elem)
+
+	case *DotsType:
+		w.node(n.Elem)
+
+	case *StructType:
+		w.fieldList(n.FieldList)
+
+	case *SelectorExpr:
+		w.node(n.X)
+		if n.Sel != nil {
+			w.node(n.Sel)
+		}
+
+	case *IndexExpr:
+		w.node(n.X)
+		w.node(n.Index)
+
+	case *SliceExpr:
+		w.node(n.X)
+		if n.Low != nil {
+			w.node(n.Low)
+		}
+		if n.High != nil {
+			w.node(n.High)
+		}
+		if n.Max != nil {
+			w.node(n.Max)
+		}
+
+	case *CallExpr:
+		w.node(n.Func)
+		w.exprList(n.ArgList)
+
+	case *Field:
+		if n.Name != nil {
+			w.node(n.Name)
+		}
+		if n.Type != nil {
+			w.node(n.Type)
+		}
+
+	// statements
+	case *EmptyStmt: // nothing to do
+	case *ContinueStmt:
+		if n.Label != nil {
+			w.node(n.Label)
+		}
+
+	case *BreakStmt:
+		if n.Label != nil {
+			w.node(n.Label)
+		}
+
+	case *GotoStmt:
+		w.node(n.Label)
+
+	case *LabeledStmt:
+		w.node(n.Label)
+		w.node(n.Stmt)
+
+	case *ExprStmt:
+		w.node(n.X)
+
+	case *IncDecStmt:
+		w.node(n.X)
+
+	case *ReturnStmt:
+		if n.Result != nil {
+			w.node(n.Result)
+		}
+
+	case *DeclStmt:
+		w.declList(n.DeclList)
+
+	case *DefineStmt:
+		w.exprList(n.Lhs)
+		w.exprList(n.Rhs)
+
+	case *AssignStmt:
+		w.exprList(n.Lhs)
+		w.exprList(n.Rhs)
+
+	case *BlockStmt:
+		w.stmtList(n.StmtList)
+
+	case *IfStmt:
+		if n.Cond != nil {
+			w.node(n.Cond)
+		}
+		if n.Block != nil {
+			w.node(n.Block)
+		}
+		if n.Else != nil {
+			w.node(n.Else)
+		}
+
+	case *ForStmt:
+		if n.Init != nil {
+			w.node(n.Init)
+		}
+		if n.Cond != nil {
+			w.node(n.Cond)
+		}
+		if n.Post != nil {
+			w.node(n.Post)
+		}
+		if n.Body != nil {
+			w.node(n.Body)
+		}
+
+	case *WhileStmt:
+		if n.Cond != nil {
+			w.node(n.Cond)
+		}
+		if n.Body != nil {
+			w.node(n.Body)
+		}
+
+	case *SwitchStmt:
+		if n.Init != nil {
+			w.node(n.Init)
+		}
+		if n.Tag != nil {
+			w.node(n.Tag)
+		}
+		for _, c := range n.Body {
+			w.node(c)
+		}
+
+	case *CaseClause:
+		w.exprList(n.CaseList)
+		w.stmtList(n.Body)
+
+	default:
+		panic(fmt.Sprintf("internal error: unknown node type %T", n))
+	}
+
+	w.v.Visit(nil)
+}
+
+func (w walker) declList(list []Decl) {
+	for _, n := range list {
+		w.node(n)
+	}
+}
+
+func (w walker) exprList(list []Expr) {
+	for _, n := range list {
+		w.node(n)
+	}
+}
+
+func (w walker) stmtList(list []Stmt) {
+	for _, n := range list {
+		w.node(n)
+	}
+}
+
+func (w walker) nameList(list []*Name) {
+	for _, n := range list {
+		w.node(n)
+	}
+}
+
+func (w walker) fieldList(list []*Field) {
+	for _, n := range list {
+		w.node(n)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package compile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"jindo/pkg/jindo/position"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// writeFiles creates the given files, keyed by slash-separated
+// path relative to dir, along with their parent directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, src := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUnresolvedImports(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"lib/found/a.paw": "space found\n",
+		"main/main.paw":   "space main\nimport \"found\"\nimport \"missing\"\n",
+	})
+
+	s, err := LoadSpace(filepath.Join(root, "main"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := UnresolvedImports(s, []string{filepath.Join(root, "lib")})
+	if want := []string{"missing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// with a file system, imports are looked up in it only
+	conf := &Config{FS: fstest.MapFS{
+		"lib/found/a.paw": {Data: []byte("space found\n")},
+		"main/main.paw":   {Data: []byte("space main\nimport \"found\"\nimport \"missing\"\n")},
+	}}
+	s, err = LoadSpace("main", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir, ok := conf.FindImport("found", []string{"lib"}); !ok || dir != "lib/found" {
+		t.Errorf("got %q, %v, want \"lib/found\", true", dir, ok)
+	}
+	if got := conf.UnresolvedImports(s, []string{"lib"}); !reflect.DeepEqual(got, []string{"missing"}) {
+		t.Errorf("got %q, want %q", got, []string{"missing"})
+	}
+	if got := conf.UnresolvedImports(s, []string{filepath.Join(root, "lib")}); !reflect.DeepEqual(got, []string{"found", "missing"}) {
+		t.Errorf("got %q, want %q", got, []string{"found", "missing"})
+	}
+}
+
+func TestFindManifest(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"jindo.mod":   "// project root\nspace example.com/proj\nversion 0.1.0\n",
+		"a/b/c/c.paw": "space c\n",
+	})
+
+	filename, err := FindManifest(filepath.Join(root, "a", "b", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "jindo.mod"); filename != want {
+		t.Errorf("got %s, want %s", filename, want)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ParseManifest(filename, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Manifest{Space: "example.com/proj", Version: "0.1.0"}); *m != want {
+		t.Errorf("got %+v, want %+v", *m, want)
+	}
+
+	dir := t.TempDir()
+	want := fmt.Sprintf("%s not found in %s or any parent directory", ManifestName, dir)
+	if _, err := FindManifest(dir); err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+}
+
+func TestForceSpace(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.paw": "space main\n",
+		"b.paw": "space other\n",
+		"c.paw": "space main\n",
+	})
+
+	if _, err := LoadSpace(dir, nil); err == nil {
+		t.Fatal("expected an error for mismatched space names")
+	}
+
+	var warnings []error
+	s, err := LoadSpace(dir, &Config{
+		ForceSpace: true,
+		Warn:       func(err error) { warnings = append(warnings, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "b.paw") {
+		t.Errorf("got warnings %v, want one for b.paw", warnings)
+	}
+	for _, f := range s.Files {
+		if f.SpaceName.Value != "main" {
+			t.Errorf("got space %s, want main", f.SpaceName.Value)
+		}
+	}
+}
+
+func TestDuplicateImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.paw": "space main\n\nimport \"geom/vec\"\nimport \"fmt\"\nimport \"geom/vec\"\n",
+	})
+	_, err := LoadSpace(dir, nil)
+	if err == nil || !strings.Contains(err.Error(), `a.paw:5:8: "geom/vec" imported twice (previous import at `) || !strings.Contains(err.Error(), "a.paw:3:8)") {
+		t.Errorf("got %v, want a duplicate import at 5:8 of the one at 3:8", err)
+	}
+
+	// paths are compared unquoted
+	dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.paw": "space main\n\nimport \"geom/vec\"\nimport `geom/vec`\n",
+	})
+	if _, err := LoadSpace(dir, nil); err == nil || !strings.Contains(err.Error(), "a.paw:4:8: `geom/vec` imported twice") {
+		t.Errorf("got %v, want a duplicate import at 4:8", err)
+	}
+
+	// the same path may be imported under different names
+	dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.paw": "space main\n\nimport \"geom/vec\"\nimport v \"geom/vec\"\n",
+		"b.paw": "space main\n\nimport \"geom/vec\"\n",
+	})
+	if _, err := LoadSpace(dir, nil); err != nil {
+		t.Errorf("got %v for aliased imports, want none", err)
+	}
+}
+
+// nopCloser turns a strings.Builder into an output file.
+type nopCloser struct{ *strings.Builder }
+
+func (nopCloser) Close() error { return nil }
+
+func TestLoadSpaceFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/geom/a.paw":  {Data: []byte("space geom\n\nfunc f() {}\n")},
+		"src/geom/b.paw":  {Data: []byte("space geom\n\nvar v int\n")},
+		"src/geom/c.txt":  {Data: []byte("not a source file")},
+		"src/other/d.paw": {Data: []byte("space other\n")},
+	}
+	out := make(map[string]*strings.Builder)
+	conf := &Config{
+		FS: fsys,
+		Create: func(name string) (io.WriteCloser, error) {
+			out[name] = new(strings.Builder)
+			return nopCloser{out[name]}, nil
+		},
+	}
+
+	s, err := LoadSpace("src/geom", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "geom" || len(s.Files) != 2 {
+		t.Fatalf("got space %s with %d files, want geom with 2", s.Name, len(s.Files))
+	}
+
+	if err := DumpSpace(s, conf); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Errorf("got %d output files, want 2", len(out))
+	}
+	for _, name := range []string{"src/geom/a.ast", "src/geom/b.ast"} {
+		if w := out[name]; w == nil || !strings.Contains(w.String(), "SpaceName: geom") {
+			t.Errorf("%s: got %v, want a dump of space geom", name, w)
+		}
+	}
+}
+
+func TestLoadSpaceFileSet(t *testing.T) {
+	fsys := fstest.MapFS{
+		"geom/a.paw": {Data: []byte("space geom\n\n\n\nvar a int\n")},
+		"geom/b.paw": {Data: []byte("space geom\nvar b int\n")},
+	}
+	conf := &Config{FS: fsys, FileSet: position.NewFileSet()}
+	s, err := LoadSpace("geom", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(conf.FileSet.Files()); n != 2 {
+		t.Fatalf("got %d files in the file set, want 2", n)
+	}
+
+	// b's declaration is on an earlier line but in a later file
+	list := []position.Pos{s.Files[1].DeclList[0].GetPos(), s.Files[0].DeclList[0].GetPos()}
+	conf.FileSet.Sort(list)
+	if got, want := list[0].String(), "geom/a.paw:5:5"; got != want {
+		t.Errorf("got %s first, want %s", got, want)
+	}
+	if conf.FileSet.Cmp(list[0], list[1]) >= 0 {
+		t.Errorf("got %s not before %s", list[0], list[1])
+	}
+}
+
+func TestMultipleSpaces(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.paw":     "space geom\n",
+		"b.paw":     "space geom\n",
+		"stray.paw": "space main\n",
+	})
+
+	_, err := LoadSpace(dir, nil)
+	var serr *MultipleSpaceError
+	if !errors.As(err, &serr) {
+		t.Fatalf("got error %v, want a *MultipleSpaceError", err)
+	}
+	a, stray := filepath.Join(dir, "a.paw"), filepath.Join(dir, "stray.paw")
+	if want := fmt.Sprintf("found spaces geom (%s) and main (%s) in %s", a, stray, dir); err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package compile
+
+import (
+	"jindo/pkg/jindo/ast"
+	"path"
+	"path/filepath"
+	"strconv"
+)
+
+// Imports returns the import paths of the files in s,
+// without duplicates and in order of first appearance.
+func (s *Space) Imports() []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, f := range s.Files {
+		for _, d := range f.DeclList {
+			d, ok := d.(*ast.ImportDecl)
+			if !ok || d.Path == nil || d.Path.Bad {
+				continue
+			}
+			path, err := strconv.Unquote(d.Path.Value)
+			if err != nil || seen[path] {
+				continue
+			}
+			seen[path] = true
+			list = append(list, path)
+		}
+	}
+	return list
+}
+
+// FindImport returns the directory of the space imported by path. The
+// search paths are tried in order; the first one holding a directory
+// with source files at path wins.
+func FindImport(path string, searchPaths []string) (dir string, ok bool) {
+	return new(Config).FindImport(path, searchPaths)
+}
+
+// UnresolvedImports returns the import paths of s which
+// cannot be located under any of the search paths.
+func UnresolvedImports(s *Space, searchPaths []string) []string {
+	return new(Config).UnresolvedImports(s, searchPaths)
+}
+
+// FindImport is like the FindImport function, but looks up directories
+// in conf.FS if set, as LoadSpace does.
+func (conf *Config) FindImport(importPath string, searchPaths []string) (dir string, ok bool) {
+	for _, root := range searchPaths {
+		var dir string
+		if conf.FS == nil {
+			dir = filepath.Join(root, filepath.FromSlash(importPath))
+		} else {
+			dir = path.Join(root, importPath)
+		}
+		if files, err := sourceFiles(conf.FS, dir); err == nil && len(files) > 0 {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// UnresolvedImports is like the UnresolvedImports function, but looks
+// up imports as conf.FindImport does.
+func (conf *Config) UnresolvedImports(s *Space, searchPaths []string) []string {
+	var list []string
+	for _, path := range s.Imports() {
+		if _, ok := conf.FindImport(path, searchPaths); !ok {
+			list = append(list, path)
+		}
+	}
+	return list
+}
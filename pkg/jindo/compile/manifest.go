@@ -0,0 +1,81 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package compile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestName is the name of the file marking the root of a project.
+const ManifestName = "jindo.mod"
+
+// A Manifest describes a project. Its file holds one directive per
+// line; blank lines and lines starting with // are ignored:
+//
+//	space example.com/project
+//	version 0.1.0
+//
+// Import paths starting with the space prefix refer to directories
+// under the project root.
+type Manifest struct {
+	Space   string // import path prefix of the project
+	Version string // project version; may be empty
+}
+
+// FindManifest returns the path of the manifest file of the project
+// containing dir, looking in dir and then in each of its parents.
+func FindManifest(dir string) (string, error) {
+	start, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	dir = start
+	for {
+		filename := filepath.Join(dir, ManifestName)
+		if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+			return filename, nil
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%s not found in %s or any parent directory", ManifestName, start)
+		}
+		dir = parent
+	}
+}
+
+// ParseManifest parses the contents of a manifest file.
+func ParseManifest(filename string, data []byte) (*Manifest, error) {
+	m := new(Manifest)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: malformed directive: %s", filename, i+1, line)
+		}
+		switch fields[0] {
+		case "space":
+			m.Space = fields[1]
+		case "version":
+			m.Version = fields[1]
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown directive: %s", filename, i+1, fields[0])
+		}
+	}
+	if m.Space == "" {
+		return nil, fmt.Errorf("%s: missing space directive", filename)
+	}
+	return m, nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+// Package compile drives the compilation of a space: it loads
+// and parses the source files of a directory and locates the
+// spaces they import.
+package compile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/parser"
+	"jindo/pkg/jindo/position"
+	"jindo/pkg/jindo/sema"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ext is the file name extension of jindo source files.
+const Ext = ".paw"
+
+// A Space is the set of source files in a directory
+// which declare the same space name.
+type Space struct {
+	Name  string
+	Dir   string
+	Files []*ast.File
+}
+
+// Config controls how a space is loaded.
+type Config struct {
+	// ForceSpace makes the space name of the first file canonical.
+	// Files declaring a different space are renamed to it in memory
+	// and reported as warnings instead of errors.
+	ForceSpace bool
+
+	// Warn, if not nil, is called with each warning.
+	Warn func(err error)
+
+	// FS, if not nil, is the file system source files are read from;
+	// directories are then slash-separated paths in FS. Otherwise source
+	// files are read from the operating system.
+	FS fs.FS
+
+	// Create, if not nil, creates the named output files written by
+	// DumpSpace. Otherwise they are created with os.Create.
+	Create func(name string) (io.WriteCloser, error)
+
+	// FileSet, if not nil, registers the position base of each parsed
+	// file, so that positions across the files of a space can be sorted.
+	FileSet *position.FileSet
+
+	// Trace, if not nil, receives a trace of the parse of each file.
+	Trace io.Writer
+}
+
+func (conf *Config) warn(err error) {
+	if conf.Warn != nil {
+		conf.Warn(err)
+	}
+}
+
+// A MultipleSpaceError is returned by LoadSpace when the source files
+// of a directory do not all declare the same space.
+type MultipleSpaceError struct {
+	Dir    string
+	Spaces []string // the expected space name, then the offending one
+	Files  []string // the files declaring the corresponding spaces
+}
+
+func (e *MultipleSpaceError) Error() string {
+	return fmt.Sprintf("found spaces %s (%s) and %s (%s) in %s", e.Spaces[0], e.Files[0], e.Spaces[1], e.Files[1], e.Dir)
+}
+
+// LoadSpace parses the source files in dir, in lexical order of their
+// names. It returns the first syntax error encountered, a
+// *MultipleSpaceError if the files do not all declare the same space,
+// or the errors reported by Validate. A nil conf is treated like a
+// zero Config.
+func LoadSpace(dir string, conf *Config) (*Space, error) {
+	if conf == nil {
+		conf = new(Config)
+	}
+
+	filenames, err := sourceFiles(conf.FS, dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no %s files in %s", Ext, dir)
+	}
+
+	s := &Space{Dir: dir}
+	for _, filename := range filenames {
+		f, err := conf.parseFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if s.Name == "" {
+			s.Name = f.SpaceName.Value
+		} else if f.SpaceName.Value != s.Name {
+			err := &MultipleSpaceError{
+				Dir:    dir,
+				Spaces: []string{s.Name, f.SpaceName.Value},
+				Files:  []string{filenames[0], filename},
+			}
+			if !conf.ForceSpace {
+				return nil, err
+			}
+			conf.warn(fmt.Errorf("%w; using %s", err, s.Name))
+			f.SpaceName.Value = s.Name
+		}
+		s.Files = append(s.Files, f)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate checks the declarations of s which are not the concern of the
+// parser. It reports each path imported twice into a file under the same
+// name, along with the position of the first import.
+func (s *Space) Validate() error {
+	var errs []error
+	for _, f := range s.Files {
+		type key struct{ path, name string }
+		seen := make(map[key]*ast.ImportDecl)
+		for _, d := range f.DeclList {
+			d, ok := d.(*ast.ImportDecl)
+			if !ok || d.Path == nil || d.Path.Bad {
+				continue
+			}
+			path, err := strconv.Unquote(d.Path.Value)
+			if err != nil {
+				continue
+			}
+			k := key{path, sema.ImportName(d)}
+			if prev := seen[k]; prev != nil {
+				errs = append(errs, fmt.Errorf("%s: %s imported twice (previous import at %s)", d.Pos, d.Path.Value, prev.Pos))
+				continue
+			}
+			seen[k] = d
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (conf *Config) parseFile(filename string) (*ast.File, error) {
+	var f fs.File
+	var err error
+	if conf.FS == nil {
+		f, err = os.Open(filename)
+	} else {
+		f, err = conf.FS.Open(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var base *position.PosBase
+	if conf.FileSet != nil {
+		base = conf.FileSet.AddFile(filename)
+	} else {
+		base = position.NewFileBase(filename)
+	}
+	if conf.Trace != nil {
+		return parser.ParseTrace(base, f, nil, conf.Trace)
+	}
+	return parser.Parse(base, f, nil)
+}
+
+func (conf *Config) create(name string) (io.WriteCloser, error) {
+	if conf.Create == nil {
+		return os.Create(name)
+	}
+	return conf.Create(name)
+}
+
+// DumpSpace writes the syntax tree of each file of s to an output file
+// named like the source file, with the extension ".ast" instead of Ext.
+// A nil conf is treated like a zero Config.
+func DumpSpace(s *Space, conf *Config) error {
+	if conf == nil {
+		conf = new(Config)
+	}
+	for _, f := range s.Files {
+		w, err := conf.create(strings.TrimSuffix(f.Pos.Filename(), Ext) + ".ast")
+		if err != nil {
+			return err
+		}
+		err = ast.Fdump(w, f)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceFiles returns the sorted names of the source files in dir,
+// which is read from fsys unless fsys is nil.
+func sourceFiles(fsys fs.FS, dir string) ([]string, error) {
+	var entries []fs.DirEntry
+	var err error
+	if fsys == nil {
+		entries, err = os.ReadDir(dir)
+	} else {
+		entries, err = fs.ReadDir(fsys, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), Ext) {
+			continue
+		}
+		if fsys == nil {
+			list = append(list, filepath.Join(dir, e.Name()))
+		} else {
+			list = append(list, path.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
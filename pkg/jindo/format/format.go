@@ -0,0 +1,74 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+// Package format implements the source rewrites done by "jindo fmt".
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"jindo/pkg/jindo/scanner"
+	"jindo/pkg/jindo/token"
+	"strings"
+)
+
+// Source formats src and returns the result. Trailing blanks are
+// removed from every line; comments and literals are left untouched.
+// If src contains lexical errors, the first one is returned.
+func Source(src []byte) ([]byte, error) {
+	var first error
+	var trailing []uint // lines with trailing blanks, with the column they start at
+	var s scanner.Scanner
+	s.InitMode(bytes.NewReader(src), func(line, col uint, msg string) {
+		if strings.HasPrefix(msg, "warning: ") {
+			trailing = append(trailing, line, col)
+			return
+		}
+		if first == nil {
+			first = fmt.Errorf("%d:%d: %s", line, col, msg)
+		}
+	}, scanner.Trailing)
+	for s.Next(); s.Token() != token.EOF; s.Next() {
+	}
+	if first != nil {
+		return nil, first
+	}
+
+	var buf bytes.Buffer
+	line := uint(1)
+	bom := 0 // a leading byte order mark does not count towards the column
+	if bytes.HasPrefix(src, []byte("\ufeff")) {
+		bom = len("\ufeff")
+	}
+	for len(src) > 0 {
+		eol, next := lineEnd(src)
+		text := src[:eol]
+		if len(trailing) > 0 && trailing[0] == line {
+			text = text[:bom+int(trailing[1])-1]
+			trailing = trailing[2:]
+		}
+		bom = 0
+		buf.Write(text)
+		buf.Write(src[eol:next]) // keep the line terminator
+		src = src[next:]
+		line++
+	}
+	return buf.Bytes(), nil
+}
+
+// lineEnd returns the end of the first line of src and the start of
+// the next one. As in the scanner, a line ends with "\n", "\r\n" or a
+// lone "\r".
+func lineEnd(src []byte) (eol, next int) {
+	eol = bytes.IndexAny(src, "\r\n")
+	switch {
+	case eol < 0:
+		return len(src), len(src)
+	case src[eol] == '\r' && eol+1 < len(src) && src[eol+1] == '\n':
+		return eol, eol + 2
+	}
+	return eol, eol + 1
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package format
+
+import "testing"
+
+func TestSourceTrailing(t *testing.T) {
+	const src = "space main  \nvar x = `a  \n`\t\n\nfunc f() {\r\n\treturn \t\r\n}\n"
+	const want = "space main\nvar x = `a  \n`\n\nfunc f() {\r\n\treturn\r\n}\n"
+	got, err := Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSourceBOM(t *testing.T) {
+	const src = "\ufeffspace main  \nvar x int\t\n"
+	const want = "\ufeffspace main\nvar x int\n"
+	got, err := Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSourceLoneCR(t *testing.T) {
+	const src = "space main  \rvar xxxxxxxxxxxxxx int\nvar y int   \n"
+	const want = "space main\rvar xxxxxxxxxxxxxx int\nvar y int\n"
+	got, err := Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
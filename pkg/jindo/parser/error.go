@@ -9,6 +9,7 @@ package parser
 import (
 	"fmt"
 	"jindo/pkg/jindo/position"
+	"sort"
 )
 
 // Error describes a syntax error. Error implements the error interface.
@@ -23,5 +24,43 @@ func (err Error) Error() string {
 
 var _ error = Error{} // verify that Error implements error
 
+// An ErrorList is a list of syntax errors.
+// The zero value for an ErrorList is an empty list ready to use.
+//
+// An ErrorList is not safe for concurrent use: error handlers of files
+// parsed in parallel into the same list must serialize their calls of
+// Add, for instance with a mutex. The position.FileSet of such files
+// needs no synchronization.
+type ErrorList []Error
+
+// Add adds an Error with the given position and message to l.
+func (l *ErrorList) Add(pos position.Pos, msg string) {
+	*l = append(*l, Error{pos, msg})
+}
+
+// Sort sorts l by position, keeping the order of errors at the same position.
+func (l ErrorList) Sort() {
+	sort.SliceStable(l, func(i, j int) bool { return l[i].Pos.Cmp(l[j].Pos) < 0 })
+}
+
+// An ErrorList implements the error interface.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns an error equivalent to l, or nil if l is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
 // An ErrorHandler is called for each error encountered reading a .go file.
 type ErrorHandler func(err error)
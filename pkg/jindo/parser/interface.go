@@ -10,7 +10,9 @@ import (
 	"io"
 	"jindo/pkg/jindo/ast"
 	"jindo/pkg/jindo/position"
+	"jindo/pkg/jindo/token"
 	"os"
+	"strings"
 )
 
 // Parse parses a single Go source file from src and returns the corresponding
@@ -24,21 +26,119 @@ import (
 // error, and the returned syntax tree is nil.
 //
 // If pragh != nil, it is called with each pragma encountered.
-func Parse(base *position.PosBase, src io.Reader, errh ErrorHandler) (_ *ast.File, first error) {
+func Parse(base *position.PosBase, src io.Reader, errh ErrorHandler) (*ast.File, error) {
+	return parse(base, src, errh, nil)
+}
+
+// ParseTrace behaves like Parse but it also writes a trace
+// of the productions being parsed to trace.
+func ParseTrace(base *position.PosBase, src io.Reader, errh ErrorHandler, trace io.Writer) (*ast.File, error) {
+	return parse(base, src, errh, trace)
+}
+
+func parse(base *position.PosBase, src io.Reader, errh ErrorHandler, trace io.Writer) (*ast.File, error) {
+	var p Parser
+	p.Reset(base, src, errh)
+	if trace != nil {
+		p.p.verbose, p.p.out = true, trace
+	}
+	return p.Parse()
+}
+
+// A Parser parses source files one after the other, reusing its buffers
+// from one file to the next; this saves allocations when many files are
+// parsed. The zero value is ready to be Reset. A Parser is not safe for
+// concurrent use; use one Parser per goroutine instead.
+type Parser struct {
+	p parser
+}
+
+// Reset prepares p to parse src, whose positions are relative to base.
+// Errors are handled as described for Parse.
+func (p *Parser) Reset(base *position.PosBase, src io.Reader, errh ErrorHandler) {
+	p.p.init(base, src, errh)
+}
+
+// Parse parses the source given to the last call of Reset and returns
+// its syntax tree and the first error, like the function Parse.
+func (p *Parser) Parse() (_ *ast.File, first error) {
 	defer func() {
-		if p := recover(); p != nil {
-			if err, ok := p.(Error); ok {
+		if x := recover(); x != nil {
+			if err, ok := x.(Error); ok {
 				first = err
 				return
 			}
-			panic(p)
+			panic(x)
+		}
+	}()
+
+	p.p.Next()
+	return p.p.fileOrNil(), p.p.first
+}
+
+// bailout is panicked by the error handler of ParseAll
+// to stop parsing once the maximum number of errors is reached.
+type bailout struct{}
+
+// ParseAll parses a single source file from src like Parse, but it collects
+// the errors, sorted by position, instead of delivering them one at a time.
+// If max > 0, parsing stops once max errors were found; in that case the
+// returned syntax tree is nil.
+func ParseAll(base *position.PosBase, src io.Reader, max int) (f *ast.File, errs ErrorList) {
+	defer func() {
+		if p := recover(); p != nil {
+			if _, ok := p.(bailout); !ok {
+				panic(p)
+			}
 		}
+		errs.Sort()
 	}()
 
 	var p parser
-	p.init(base, src, errh)
+	p.init(base, src, func(err error) {
+		if err, ok := err.(Error); ok {
+			errs = append(errs, err)
+		}
+		if max > 0 && len(errs) >= max {
+			panic(bailout{})
+		}
+	})
+	p.Next()
+	return p.fileOrNil(), errs
+}
+
+// ParseExprList parses x as a comma-separated list of expressions, as
+// typed into a REPL, and returns the list and the first error found, if
+// any. It is an error for tokens to remain after the list. On errors,
+// the returned list holds an *ast.BadExpr in place of each element that
+// could not be parsed, so callers can tell which one failed and why.
+func ParseExprList(x string) (list []ast.Expr, first error) {
+	var p parser
+	var reason string // message of the first error in the current element
+	p.init(position.NewFileBase(""), strings.NewReader(x), func(err error) {
+		if reason == "" {
+			reason = strings.TrimPrefix(err.(Error).Msg, "syntax error: ")
+		}
+	})
 	p.Next()
-	return p.fileOrNil(), p.first
+	for {
+		errcnt := p.errcnt
+		reason = ""
+		e := p.expr()
+		if p.errcnt > errcnt {
+			e = ast.NewBadExpr(e.GetPos(), reason)
+		}
+		list = append(list, e)
+		if !p.got(token.Comma) {
+			break
+		}
+	}
+	// accept the automatically inserted semicolon at the end of the input
+	if p.Token() == token.Semi && p.Literal() != "semicolon" {
+		p.Next()
+	}
+	p.want(token.EOF)
+	return list, p.first
 }
 
 // ParseFile behaves like Parse but it reads the source from the named file.
@@ -13,7 +13,6 @@ import (
 	"jindo/pkg/jindo/position"
 	"jindo/pkg/jindo/scanner"
 	"jindo/pkg/jindo/token"
-	"os"
 	"strconv"
 	"strings"
 )
@@ -27,7 +26,13 @@ type parser struct {
 	first   error
 	errcnt  int // number of errors encountered
 	verbose bool
-	fnest   int // function nesting level (for error handling)
+	out     io.Writer // destination of the trace, valid if verbose is set
+	fnest   int       // function nesting level (for error handling)
+	xnest   int       // expression nesting level (for complit ambiguity resolution)
+
+	// comments seen before the space clause; nil once it was parsed
+	leading []ast.Comment
+	inDoc   bool // collecting leading comments
 }
 
 // nil means error has occured
@@ -39,14 +44,17 @@ func (p *parser) fileOrNil() *ast.File {
 	// SourceFile = Space ";" { TopLevelDecl ";" } .
 	f := new(ast.File)
 	f.Pos = p.pos()
-	if !p.got(token.Space) {
-		fmt.Println("expected space, got '" + p.Token().String() + "'")
-		os.Exit(-1)
-		return nil
+	f.Doc = docComment(p.leading, p.Line())
+	p.leading, p.inDoc = nil, false
+	// A missing space clause is reported, but the declarations
+	// are still parsed so that callers get a partial file.
+	if p.got(token.Space) {
+		f.SpaceName = p.name()
+		p.print("space: " + f.SpaceName.Value)
+		p.want(token.Semi)
+	} else {
+		p.syntaxError("space clause must be first")
 	}
-	f.SpaceName = p.name()
-	p.print("space: " + f.SpaceName.Value)
-	p.want(token.Semi)
 
 	// TopLevelDecl = Declaration | FuncDecl | OperDecl .
 	// Accept import declarations anywhere for error tolerance, but complain.
@@ -56,27 +64,37 @@ func (p *parser) fileOrNil() *ast.File {
 		if p.Token() == token.Import && prev != token.Import {
 			p.syntaxError("imports must appear before other declarations")
 		}
-		prev = p.Token()
+		if p.Token() != token.Semi {
+			prev = p.Token()
+		}
 
 		switch p.Token() {
 		case token.Import:
 			p.Next()
-			f.DeclList = p.appendGroup(f.DeclList, p.importDecl)
+			f.DeclList, _ = p.appendGroup(f.DeclList, p.importDecl)
 		case token.Type:
 			p.Next()
-			f.DeclList = p.appendGroup(f.DeclList, p.typeDecl)
+			f.DeclList, _ = p.appendGroup(f.DeclList, p.typeDecl)
+
+		case token.Const:
+			p.Next()
+			f.DeclList, _ = p.appendGroup(f.DeclList, p.constDecl)
 
 		case token.Var:
 			p.Next()
-			f.DeclList = p.appendGroup(f.DeclList, p.varDecl)
+			f.DeclList, _ = p.appendGroup(f.DeclList, p.varDecl)
 
 		case token.Func:
 			p.Next()
-			f.DeclList = p.appendGroup(f.DeclList, p.funcDeclOrNil)
+			if d := p.funcDeclOrNil(nil); d != nil {
+				f.DeclList = append(f.DeclList, d)
+			}
 
 		case token.Oper:
 			p.Next()
-			f.DeclList = p.appendGroup(f.DeclList, p.operDecl)
+			if d := p.operDecl(nil); d != nil {
+				f.DeclList = append(f.DeclList, d)
+			}
 
 		case token.Semi:
 			p.Next()
@@ -90,6 +108,7 @@ func (p *parser) fileOrNil() *ast.File {
 			p.Next()
 		}
 	}
+	f.EOF = p.pos()
 	return f
 }
 
@@ -113,9 +132,9 @@ func (p *parser) print(msg string) {
 		return
 	}
 	if line != int(p.Line()) {
-		fmt.Printf("line %-4d%s%s\n", p.Line(), p.indent, msg)
+		fmt.Fprintf(p.out, "line %-4d%s%s\n", p.Line(), p.indent, msg)
 	} else {
-		fmt.Printf("         %s%s\n", p.indent, msg)
+		fmt.Fprintf(p.out, "         %s%s\n", p.indent, msg)
 	}
 	line = int(p.Line())
 }
@@ -126,6 +145,18 @@ func (p *parser) want(tok token.Token) {
 	}
 }
 
+// wantOrSync is like want, but if the current token is not tok it also
+// skips tokens until it finds tok or a token of followlist, to avoid a
+// cascade of errors. It reports whether tok was consumed.
+func (p *parser) wantOrSync(tok token.Token, followlist ...token.Token) bool {
+	if p.got(tok) {
+		return true
+	}
+	p.syntaxError(fmt.Sprintf("expected %s, got %s", tok, p.Token()))
+	p.advance(append(followlist, tok)...)
+	return p.got(tok)
+}
+
 func (p *parser) got(tok token.Token) bool {
 	if p.Token() == tok {
 		p.Next()
@@ -134,6 +165,20 @@ func (p *parser) got(tok token.Token) bool {
 	return false
 }
 
+// docComment returns the block of adjacent comments at the end of
+// list that ends on the line preceding line, or nil if there is none.
+func docComment(list []ast.Comment, line uint) []ast.Comment {
+	i := len(list)
+	for i > 0 && list[i-1].EndLine()+1 == line {
+		i--
+		line = list[i].Pos.Line()
+	}
+	if i == len(list) {
+		return nil
+	}
+	return list[i:]
+}
+
 func commentText(s string) string {
 	if s[:2] == "/*" {
 		return s[2 : len(s)-2] // lop off /* and */
@@ -151,13 +196,17 @@ func commentText(s string) string {
 func (p *parser) init(file *position.PosBase, r io.Reader, errh ErrorHandler) {
 	p.errh = errh
 	p.file = file
-	p.Scanner.Init(r,
+	p.Scanner.InitMode(r,
 		func(line, col uint, msg string) {
 			if msg[0] != '/' {
 				p.errorAt(p.posAt(line, col), msg)
 				return
 			}
 
+			if p.inDoc {
+				p.leading = append(p.leading, ast.Comment{Pos: p.posAt(line, col), Text: msg})
+			}
+
 			// otherwise it must be a comment containing a line or go: directive.
 			// //line directives must be at the start of the line (column colbase).
 			// /*line*/ directives can be anywhere in the line.
@@ -187,10 +236,17 @@ func (p *parser) init(file *position.PosBase, r io.Reader, errh ErrorHandler) {
 		//	p.errorAt(p.posAt(line, col), msg)
 		//
 		//},
+		scanner.Comments,
 	)
 	p.base = file
+	p.first = nil
+	p.errcnt = 0
+	p.verbose, p.out = false, nil
 	p.fnest = 0
-	p.indent = nil
+	p.xnest = 0
+	p.indent = p.indent[:0]
+	p.leading = nil
+	p.inDoc = true
 }
 
 func tokstring(tok token.Token) string {
@@ -282,11 +338,32 @@ func (p *parser) gotAssign() bool {
 
 // ----------------------------------------------------------------------------
 // Declarations
-func (p *parser) appendGroup(list []ast.Decl, f func(group *ast.Group) ast.Decl) []ast.Decl {
+
+// appendGroup(f) = f | "(" { f ";" } ")" . // ";" is optional before ")"
+//
+// For a group, appendGroup also returns the position of the closing ")".
+func (p *parser) appendGroup(list []ast.Decl, f func(group *ast.Group) ast.Decl) (_ []ast.Decl, rparen position.Pos) {
+	if p.got(token.Lparen) {
+		g := new(ast.Group)
+		for p.Token() != token.EOF && p.Token() != token.Rparen {
+			if x := f(g); x != nil {
+				list = append(list, x)
+			}
+			if !p.got(token.Semi) && p.Token() != token.Rparen {
+				p.syntaxError("in grouped declaration; possibly missing semicolon or newline or )")
+				p.advance(token.Semi, token.Rparen)
+				p.got(token.Semi)
+			}
+		}
+		rparen = p.pos()
+		p.want(token.Rparen)
+		return list, rparen
+	}
+
 	if x := f(nil); x != nil {
 		list = append(list, x)
 	}
-	return list
+	return list, rparen
 }
 
 // TypeSpec = identifier [ TypeParams ] [ "=" ] Type .
@@ -304,16 +381,39 @@ func (p *parser) typeDecl(group *ast.Group) ast.Decl {
 	d.Type = p.typeOrNil()
 
 	if d.Type == nil {
-		d.Type = p.badExpr()
-		p.syntaxError("in type declaration")
+		d.Type = p.badExpr("in type declaration")
 	} else if p.verbose {
 		p.print("id: " + d.Name.Value)
-		p.print("type: " + d.Type.(*ast.Name).Value)
+		p.print("type: " + String(d.Type))
 	}
 	return d
 }
 
-// VarDecl = "var" identifier ( Type [ "=" ast.Expr ] | "=" ast.Expr ) .
+// ConstDecl = "const" identifier [ Type ] "=" ast.Expr .
+func (p *parser) constDecl(group *ast.Group) ast.Decl {
+	if p.verbose {
+		defer p.trace("constDecl")()
+	}
+
+	d := new(ast.ConstDecl)
+	d.Pos = p.pos()
+	d.Group = group
+
+	d.NameList = p.name()
+	p.print("id: " + d.NameList.Value)
+	if p.Token() != token.Assign && p.Token() != token.Define {
+		d.Type = p.typeOrNil()
+	}
+	if !p.gotAssign() {
+		d.Values = p.badExpr("expecting = in const declaration")
+		return d
+	}
+	d.Values = p.expr()
+
+	return d
+}
+
+// VarDecl = "var" IdentifierList ( Type [ "=" ExpressionList ] | "=" ExpressionList ) .
 func (p *parser) varDecl(group *ast.Group) ast.Decl {
 	if p.verbose {
 		defer p.trace("varDecl")()
@@ -323,24 +423,71 @@ func (p *parser) varDecl(group *ast.Group) ast.Decl {
 	d.Pos = p.pos()
 	d.Group = group
 
-	d.NameList = p.name()
-	p.print("id: " + d.NameList.Value)
+	d.NameList = p.nameList(p.name())
+	for _, n := range d.NameList {
+		p.print("id: " + n.Value)
+	}
 	if p.gotAssign() {
-		d.Values = p.expr()
+		d.Values = p.values(d.Pos, len(d.NameList))
 	} else {
-		if p.Token() != token.Name {
-			p.syntaxError("expecting name")
+		d.Type = p.typeOrNil()
+		if d.Type == nil {
+			p.syntaxError("expecting type")
 			p.Next()
 			return nil
 		}
-
-		d.Type = p.name()
-		p.print("type: " + d.Type.(*ast.Name).Value)
+		p.print("type: " + String(d.Type))
+		if p.gotAssign() {
+			d.Values = p.values(d.Pos, len(d.NameList))
+		}
 	}
 
 	return d
 }
 
+// values parses the expression list of a declaration of n names
+// at pos. A list of more than one expression yields an *ast.ListExpr.
+func (p *parser) values(pos position.Pos, n int) ast.Expr {
+	list := p.exprList()
+	p.checkCount(pos, n, list)
+	if len(list) == 1 {
+		return list[0]
+	}
+	x := new(ast.ListExpr)
+	x.Pos = list[0].GetPos()
+	x.ElemList = list
+	return x
+}
+
+// ExpressionList = Expression { "," Expression } .
+func (p *parser) exprList() []ast.Expr {
+	list := []ast.Expr{p.expr()}
+	for p.got(token.Comma) {
+		list = append(list, p.expr())
+	}
+	return list
+}
+
+// checkCount reports an assignment mismatch at pos unless the n
+// variables are matched by as many values in rhs, or by a single
+// call, which may return multiple results.
+func (p *parser) checkCount(pos position.Pos, n int, rhs []ast.Expr) {
+	if len(rhs) == n {
+		return
+	}
+	if _, ok := rhs[0].(*ast.CallExpr); ok && len(rhs) == 1 {
+		return
+	}
+	p.syntaxErrorAt(pos, fmt.Sprintf("assignment mismatch: %d variable%s but %d value%s", n, plural(n), len(rhs), plural(len(rhs))))
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
 // TypeDecl =
 
 // FuncDecl = "func" FuncName Signature FuncBody .
@@ -350,11 +497,24 @@ func (p *parser) funcDeclOrNil(group *ast.Group) ast.Decl {
 		defer p.trace("funcDecl")()
 	}
 
-	// func name(name type) type {Body}
+	// func [(recv type)] name(name type) type {Body}
 	d := new(ast.FuncDecl)
 	d.Pos = p.pos()
 	d.Group = group
 
+	if p.got(token.Lparen) {
+		rcvr, _ := p.paramlist()
+		switch len(rcvr) {
+		case 0:
+			p.errorAt(d.Pos, "method has no receiver")
+		default:
+			p.errorAt(d.Pos, "method has multiple receivers")
+			fallthrough
+		case 1:
+			d.Recv = rcvr[0]
+		}
+	}
+
 	if p.Token() != token.Name {
 		p.errorAt(p.pos(), "expecting name")
 		return nil
@@ -365,7 +525,7 @@ func (p *parser) funcDeclOrNil(group *ast.Group) ast.Decl {
 	p.print("id: " + d.Name.Value)
 
 	// Signature
-	d.Param, d.Return = p.funcType()
+	d.Param, d.Rparen, d.Return = p.funcType()
 
 	// FuncBody
 	if p.Token() == token.Lbrace {
@@ -374,12 +534,15 @@ func (p *parser) funcDeclOrNil(group *ast.Group) ast.Decl {
 	return d
 }
 
+// FuncDecl = "func" [ Receiver ] identifier Signature [ FuncBody ] .
+// Receiver = "(" Param ")" .
+//
 // OperDecl = "oper" Receiver OperName OperOperand ReturnType OperBody .
 // Receiver = "(" Param ")" .
 // OperName =
 //
-//	"add" | "sub" | "mul" | "div" | "mod" |
-//	"radd" | "rsub" | "rmul" | "rdiv" | "rmod" .
+//	"not" | "add" | "sub" | "mul" | "div" | "eql" | "gtr" | "rem" |
+//	"rnot" | "radd" | "rsub" | "rmul" | "rdiv" | "reql" | "rgtr" | "rrem" .
 //
 // OperOperand = "(" Param ")" .
 // ReturnType = Type .
@@ -393,30 +556,50 @@ func (p *parser) operDecl(group *ast.Group) ast.Decl {
 	d.Pos = p.pos()
 	d.Group = group
 	d.TypeL = p.singleParam()
+	if d.TypeL == nil || d.TypeL.Name == nil {
+		p.advance(declStart...)
+		return nil
+	}
 
+	// the operator word is looked up in the table of overloadable
+	// operators; the rest of an unknown operator's declaration is
+	// still parsed, to avoid follow-on errors, but then dropped
 	name := p.name()
 	op := token.OperOrNil(name.Value)
 	if !op.IsOperOverload() {
-		p.errorAt(p.pos(), "Unexpected Operator name")
-		return nil
+		p.syntaxErrorAt(name.Pos, "unknown operator name "+name.Value)
 	}
 
 	d.Oper = op
-	p.Next()
-	p.print("oper type: " + d.Oper.String())
+	p.print("oper type: " + d.Oper.OverloadName())
 	d.TypeR = p.singleParam()
+	if d.TypeR == nil || d.TypeR.Name == nil {
+		p.advance(declStart...)
+		return nil
+	}
+	p.checkDuplicate([]*ast.Field{d.TypeL}, d.TypeR.Name)
 	p.print("operands: " + d.TypeL.Name.Value + " " + d.TypeR.Name.Value)
-	if p.Token() != token.Name {
+	d.Return = p.typeOrNil()
+	if d.Return == nil {
 		p.errorAt(p.pos(), "expecting type")
+		if p.Token() == token.Lbrace {
+			p.funcBody() // skip the body, to avoid follow-on errors
+		}
 		return nil
 	}
-	d.Return = p.name()
-	p.print("return type: " + d.Return.(*ast.Name).Value)
+	p.print("return type: " + String(d.Return))
 	d.Body = p.funcBody()
 
+	if !op.IsOperOverload() {
+		return nil
+	}
 	return d
 }
 
+// declStart lists the tokens that start a top-level declaration; a
+// malformed declaration is skipped up to one of them.
+var declStart = []token.Token{token.Import, token.Type, token.Const, token.Var, token.Func, token.Oper}
+
 // FuncBody = Block .
 func (p *parser) funcBody() *ast.BlockStmt {
 	p.fnest++
@@ -425,20 +608,45 @@ func (p *parser) funcBody() *ast.BlockStmt {
 	return body
 }
 
-func (p *parser) funcType() ([]*ast.Field, ast.Expr) {
-	params := make([]*ast.Field, 0)
+func (p *parser) funcType() ([]*ast.Field, position.Pos, ast.Expr) {
 	p.want(token.Lparen)
-	params = p.paramlist()
-	ftype := p.typeOrNil()
-	switch ftype.(type) {
-	case *ast.Name:
-		p.print("return type: " + ftype.(*ast.Name).Value)
-	case *ast.SliceType:
-		slice := ftype.(*ast.SliceType)
+	params, rparen := p.paramlist()
+	var ftype ast.Expr
+	if p.Token() == token.Lparen {
+		ftype = p.resultList()
+	} else {
+		ftype = p.typeOrNil()
+	}
+	if ftype != nil {
+		p.print("return type: " + String(ftype))
+	}
+	return params, rparen, ftype
+}
 
-		p.print("return type: slice of " + slice.Elem.(*ast.Name).Value)
+// Result = Type | "(" [ Type { "," Type } ] ")" .
+// resultList parses a parenthesized result list into an *ast.ListExpr,
+// or nil if the list is empty.
+func (p *parser) resultList() ast.Expr {
+	x := new(ast.ListExpr)
+	x.Pos = p.pos()
+	p.want(token.Lparen)
+	for p.Token() != token.EOF && p.Token() != token.Rparen {
+		t := p.typeOrNil()
+		if t == nil {
+			p.syntaxError("expecting type")
+			break
+		}
+		x.ElemList = append(x.ElemList, t)
+		if !p.got(token.Comma) {
+			break
+		}
 	}
-	return params, ftype
+	x.Rparen = p.pos()
+	p.wantOrSync(token.Rparen, token.Lbrace, token.Semi)
+	if len(x.ElemList) == 0 {
+		return nil
+	}
+	return x
 }
 
 // ----------------------------------------------------------------------------
@@ -453,6 +661,10 @@ func (p *parser) simpleStmt(ls ast.Expr, keyword token.Token) ast.SimpleStmt {
 	if ls == nil {
 		ls = p.expr()
 	}
+	lhs := []ast.Expr{ls}
+	for p.got(token.Comma) {
+		lhs = append(lhs, p.expr())
+	}
 
 	pos := p.pos()
 	switch p.Token() {
@@ -465,17 +677,41 @@ func (p *parser) simpleStmt(ls ast.Expr, keyword token.Token) ast.SimpleStmt {
 			op = token.NoneOp
 		}
 		p.Next()
-		return p.assignStmt(pos, op, ls, p.expr())
+		rhs := p.exprList()
+		if op != token.NoneOp && (len(lhs) > 1 || len(rhs) > 1) {
+			p.syntaxErrorAt(pos, fmt.Sprintf("assignment operation %s= requires single-valued expressions", op))
+		} else {
+			p.checkCount(pos, len(lhs), rhs)
+		}
+		return p.assignStmt(pos, op, lhs, rhs)
 	case token.Define:
 		if p.verbose {
 			defer p.trace("shortVarDecl")()
 		}
 		p.Next()
-		return p.defineStmt(pos, ls, p.expr())
+		rhs := p.exprList()
+		p.checkCount(pos, len(lhs), rhs)
+		return p.defineStmt(pos, lhs, rhs)
+	case token.IncOp:
+		if p.verbose {
+			defer p.trace("incDecStmt")()
+		}
+		if len(lhs) > 1 {
+			p.syntaxError("expecting := or = or comma")
+		}
+		s := new(ast.IncDecStmt)
+		s.Pos = pos
+		s.X = ls
+		s.Op = p.Op()
+		p.Next()
+		return s
 	default:
 		if p.verbose {
 			defer p.trace("exprStmt")()
 		}
+		if len(lhs) > 1 {
+			p.syntaxError("expecting := or = or comma")
+		}
 		s := new(ast.ExprStmt)
 		s.Pos = ls.GetPos()
 		s.X = ls
@@ -493,14 +729,14 @@ func (p *parser) declStmt(f func(*ast.Group) ast.Decl) *ast.DeclStmt {
 	s.Pos = p.pos()
 
 	p.Next() // token.Const, token.Type, or token.Var
-	s.DeclList = p.appendGroup(nil, f)
+	s.DeclList, s.Rparen = p.appendGroup(nil, f)
 
 	return s
 }
 
-// Assignment = ast.Expr assign_op ast.Expr .
+// Assignment = ExpressionList assign_op ExpressionList .
 // assign_op = [ ass_op | mul_op ] "=" .
-func (p *parser) assignStmt(pos position.Pos, op token.Operator, lhs, rhs ast.Expr) *ast.AssignStmt {
+func (p *parser) assignStmt(pos position.Pos, op token.Operator, lhs, rhs []ast.Expr) *ast.AssignStmt {
 	a := new(ast.AssignStmt)
 	a.Pos = pos
 	a.Op = op
@@ -509,7 +745,8 @@ func (p *parser) assignStmt(pos position.Pos, op token.Operator, lhs, rhs ast.Ex
 	return a
 }
 
-func (p *parser) defineStmt(pos position.Pos, lhs, rhs ast.Expr) *ast.DefineStmt {
+// ShortVarDecl = ExpressionList ":=" ExpressionList .
+func (p *parser) defineStmt(pos position.Pos, lhs, rhs []ast.Expr) *ast.DefineStmt {
 	s := new(ast.DefineStmt)
 	s.Pos = pos
 	s.Lhs = lhs
@@ -518,6 +755,8 @@ func (p *parser) defineStmt(pos position.Pos, lhs, rhs ast.Expr) *ast.DefineStmt
 }
 
 // Block = "{" StatementList "}" .
+// If the opening brace is missing, blockStmt reports an error
+// and returns an empty block rather than nil.
 func (p *parser) blockStmt(context string) *ast.BlockStmt {
 	if p.verbose {
 		defer p.trace("blockStmt")()
@@ -527,12 +766,13 @@ func (p *parser) blockStmt(context string) *ast.BlockStmt {
 	// people coming from C may forget that braces are mandatory in Go
 	if !p.got(token.Lbrace) {
 		p.syntaxError("expecting '{'")
-		return nil
+		s.Rbrace = s.Pos
+		return s
 	}
 	s.StmtList = p.stmtList()
 
 	s.Rbrace = p.pos()
-	p.want(token.Rbrace)
+	p.wantOrSync(token.Rbrace)
 
 	return s
 }
@@ -543,14 +783,14 @@ func (p *parser) stmtList() (l []ast.Stmt) {
 		defer p.trace("stmtList")()
 	}
 
-	for p.Token() != token.EOF && p.Token() != token.Rbrace {
+	for p.Token() != token.EOF && p.Token() != token.Rbrace && p.Token() != token.Case && p.Token() != token.Default {
 		s := p.stmtOrNil()
 		if s == nil {
 			break
 		}
 		l = append(l, s)
 		// ";" is optional before "}"
-		if !p.got(token.Semi) && p.Token() != token.Rbrace {
+		if !p.got(token.Semi) && p.Token() != token.Rbrace && p.Token() != token.Case && p.Token() != token.Default {
 			p.syntaxError("at end of statement")
 			p.got(token.Semi) // avoid spurious empty statement
 		}
@@ -560,8 +800,8 @@ func (p *parser) stmtList() (l []ast.Stmt) {
 
 // Statement =
 //
-//	Declaration | ast.SimpleStmt | ReturnStmt | BreakStmt | ContinueStmt |
-//	Block | IfStmt | ForStmt .
+//	Declaration | LabeledStmt | ast.SimpleStmt | ReturnStmt | BreakStmt |
+//	ContinueStmt | GotoStmt | Block | IfStmt | ForStmt | SwitchStmt .
 func (p *parser) stmtOrNil() ast.Stmt {
 	if p.verbose {
 		defer p.trace("stmt")()
@@ -570,14 +810,21 @@ func (p *parser) stmtOrNil() ast.Stmt {
 	if p.Token() == token.Name {
 		p.print("lhs:")
 		lhs := p.expr()
+		if label, ok := lhs.(*ast.Name); ok && p.Token() == token.Colon {
+			return p.labeledStmtOrNil(label)
+		}
 		return p.simpleStmt(lhs, 0)
 	}
 	switch p.Token() {
+	case token.Const:
+		return p.declStmt(p.constDecl)
+	case token.Type:
+		return p.declStmt(p.typeDecl)
 	case token.Var:
 		return p.declStmt(p.varDecl)
 	case token.Lbrace:
 		return p.blockStmt("")
-	case token.Literal, token.Name:
+	case token.Literal, token.Name, token.Star:
 		return p.simpleStmt(nil, 0)
 	case token.For:
 		return p.forStmt()
@@ -586,6 +833,8 @@ func (p *parser) stmtOrNil() ast.Stmt {
 		return p.whileStmt()
 	case token.If:
 		return p.ifStmt()
+	case token.Switch:
+		return p.switchStmt()
 	case token.Return:
 		s := new(ast.ReturnStmt)
 		s.Pos = p.pos()
@@ -598,6 +847,23 @@ func (p *parser) stmtOrNil() ast.Stmt {
 		s := new(ast.BreakStmt)
 		s.Pos = p.pos()
 		p.Next()
+		if p.Token() == token.Name {
+			s.Label = p.name()
+		}
+		return s
+	case token.Continue:
+		s := new(ast.ContinueStmt)
+		s.Pos = p.pos()
+		p.Next()
+		if p.Token() == token.Name {
+			s.Label = p.name()
+		}
+		return s
+	case token.Goto:
+		s := new(ast.GotoStmt)
+		s.Pos = p.pos()
+		p.Next()
+		s.Label = p.name()
 		return s
 	case token.Semi:
 		func() { defer p.trace("empty stmt")() }()
@@ -620,6 +886,10 @@ func (p *parser) expr() ast.Expr {
 }
 
 // ast.Expr = UnaryExpr | ast.Expr binary_op ast.Expr .//a+b*x
+//
+// Binary operations are left-associative and kept in source order: the
+// operator and operands of a comparison such as a < b are recorded as
+// written, so that a < b and b > a yield different trees.
 func (p *parser) binaryExpr(prec int) ast.Expr {
 	// don't p.verbose binaryExpr - only leads to overly nested p.verbose output
 
@@ -633,13 +903,6 @@ func (p *parser) binaryExpr(prec int) ast.Expr {
 		p.Next()
 		t.X = x
 		t.Y = p.binaryExpr(tprec)
-
-		switch t.Op {
-		case token.Lss:
-			t.Op = token.Gtr
-			t.X, t.Y = t.Y, t.X
-		}
-
 		x = t
 	}
 	return x
@@ -661,16 +924,25 @@ func (p *parser) unaryExpr() ast.Expr {
 			x.X = p.unaryExpr()
 			return x
 
-			//case And:
-			//	x := new(Operation)
-			//	x.pos = p.pos()
-			//	x.Op = And
-			//	p.Next()
-			//	// unaryExpr may have returned a parenthesized composite gotLiteral
-			//	// (see comment in operand) - remove parentheses if any
-			//	x.X = Unparen(p.unaryExpr())
-			//	return x
+		case token.And:
+			x := new(ast.Operation)
+			x.Pos = p.pos()
+			x.Op = token.And
+			p.Next()
+			// unaryExpr may have returned a parenthesized composite literal
+			// (see comment in pexpr) - remove parentheses if any
+			x.X = Unparen(p.unaryExpr())
+			return x
 		}
+
+	case token.Star:
+		// '*' is scanned as its own token; as a prefix it dereferences
+		x := new(ast.Operation)
+		x.Pos = p.pos()
+		x.Op = token.Mul
+		p.Next()
+		x.X = p.unaryExpr()
+		return x
 	}
 	return p.pexpr()
 }
@@ -680,7 +952,6 @@ func (p *parser) operand() (rtn ast.Expr) {
 		defer p.trace("operand")()
 	}
 
-	rtn = &ast.BadExpr{}
 	tok := p.Token().String()
 	switch p.Token() {
 	case token.Name:
@@ -690,10 +961,27 @@ func (p *parser) operand() (rtn ast.Expr) {
 		rtn = p.sliceLit()
 		p.print(tok + "(" + ")")
 
+	case token.Map:
+		rtn = p.mapType()
+
 	case token.Literal:
 		lit := p.literal()
 		rtn = lit
 		p.print(tok + "(" + lit.Value + ")")
+
+	case token.Lparen:
+		x := new(ast.ParenExpr)
+		x.Pos = p.pos()
+		p.Next()
+		p.xnest++
+		x.X = p.expr()
+		p.xnest--
+		x.Rparen = p.pos()
+		p.wantOrSync(token.Rparen, token.Semi, token.Rbrace)
+		rtn = x
+
+	default:
+		rtn = p.badExpr("expecting expression")
 	}
 	return
 }
@@ -706,6 +994,7 @@ func (p *parser) operand() (rtn ast.Expr) {
 //
 // Selector       = "." identifier .
 // Call			  = "(" [ ast.ExprList ] ")" .
+// CompositeLit   = LiteralType "{" [ ElementList [ "," ] ] "}" .
 func (p *parser) pexpr() ast.Expr {
 	if p.verbose {
 		defer p.trace("pexpr")()
@@ -732,11 +1021,50 @@ loop:
 			}
 		case token.Lbrack:
 			// pexpr '[' expr ']'
-			t := new(ast.IndexExpr)
+			// pexpr '[' expr? ':' expr? ']'
+			// pexpr '[' expr? ':' expr ':' expr ']'
+			p.Next()
+			p.xnest++
+			var i ast.Expr
+			if p.Token() != token.Colon {
+				i = p.expr()
+				if p.Token() != token.Colon {
+					// x[i]
+					t := new(ast.IndexExpr)
+					t.Pos = pos
+					t.X = x
+					t.Index = i
+					p.xnest--
+					t.Rbrack = p.pos()
+					p.want(token.Rbrack)
+					x = t
+					break
+				}
+			}
+
+			// x[i:...
+			t := new(ast.SliceExpr)
 			t.Pos = pos
 			t.X = x
-			p.Next()
-			t.Index = p.expr()
+			t.Low = i
+			p.want(token.Colon)
+			if p.Token() != token.Colon && p.Token() != token.Rbrack {
+				t.High = p.expr()
+			}
+			if p.Token() == token.Colon {
+				t.Full = true
+				if t.High == nil {
+					p.syntaxError("middle index required in 3-index slice")
+				}
+				p.Next()
+				if p.Token() != token.Rbrack {
+					t.Max = p.expr()
+				} else {
+					p.syntaxError("final index required in 3-index slice")
+				}
+			}
+			p.xnest--
+			t.Rbrack = p.pos()
 			p.want(token.Rbrack)
 			x = t
 		case token.Lparen:
@@ -744,9 +1072,25 @@ loop:
 			t := new(ast.CallExpr)
 			t.Pos = pos
 			t.Func = x
-			t.ArgList = p.argList()
+			t.ArgList, t.Rparen = p.argList()
 			x = t
 
+		case token.Lbrace:
+			// a named type followed by "{" is a composite literal, except
+			// at the top level of a statement header where the "{" opens
+			// the block (as in "if x {"); parenthesize the literal there
+			switch x.(type) {
+			case *ast.Name, *ast.SelectorExpr:
+				if p.xnest < 0 {
+					break loop
+				}
+				x = p.complitexpr(x)
+			case *ast.MapType:
+				x = p.complitexpr(x)
+			default:
+				break loop
+			}
+
 		default:
 			break loop
 		}
@@ -755,18 +1099,183 @@ loop:
 	return x
 }
 
+// LiteralValue = "{" [ ElementList [ "," ] ] "}" .
+// ElementList  = Element { "," Element } .
+// Element      = [ Key ":" ] Expr .
+func (p *parser) complitexpr(typ ast.Expr) *ast.CompositeLit {
+	if p.verbose {
+		defer p.trace("complitexpr")()
+	}
+	x := new(ast.CompositeLit)
+	x.Pos = p.pos()
+	x.Type = typ
+	p.want(token.Lbrace)
+	p.xnest++
+	for p.Token() != token.EOF && p.Token() != token.Rbrace {
+		e := p.expr()
+		if p.Token() == token.Colon {
+			l := new(ast.KeyValueExpr)
+			l.Pos = p.pos()
+			p.Next()
+			l.Key = e
+			l.Value = p.expr()
+			e = l
+			x.NKeys++
+		}
+		x.ElemList = append(x.ElemList, e)
+		if !p.got(token.Comma) {
+			break
+		}
+	}
+	p.xnest--
+	x.Rbrace = p.pos()
+	p.wantOrSync(token.Rbrace, token.Semi)
+	return x
+}
+
 // ----------------------------------------------------------------------------
 // Types
 func (p *parser) typeOrNil() ast.Expr {
 	switch p.Token() {
 	case token.Name:
-		return p.name()
+		return p.qualifiedName()
 	case token.Lbrack:
 		return p.sliceType()
+	case token.Struct:
+		return p.structType()
+	case token.Star:
+		return p.pointerType()
+	case token.Map:
+		return p.mapType()
 	}
 	return nil
 }
 
+// MapType = "map" "[" KeyType "]" ElementType .
+func (p *parser) mapType() *ast.MapType {
+	t := new(ast.MapType)
+	t.Pos = p.pos()
+	p.Next()
+	p.want(token.Lbrack)
+	t.Key = p.typeOrNil()
+	if t.Key == nil {
+		t.Key = p.badExpr("missing key type in map type")
+	}
+	p.want(token.Rbrack)
+	t.Value = p.typeOrNil()
+	if t.Value == nil {
+		t.Value = p.badExpr("missing element type in map type")
+	}
+	return t
+}
+
+// PointerType = "*" Type .
+func (p *parser) pointerType() *ast.PointerType {
+	t := new(ast.PointerType)
+	t.Pos = p.pos()
+	p.Next()
+	t.Elem = p.typeOrNil()
+	if t.Elem == nil {
+		t.Elem = p.badExpr("missing element type in pointer type")
+	}
+	return t
+}
+
+// StructType = "struct" "{" { FieldDecl ";" } "}" .
+func (p *parser) structType() *ast.StructType {
+	if p.verbose {
+		defer p.trace("structType")()
+	}
+
+	typ := new(ast.StructType)
+	typ.Pos = p.pos()
+
+	p.want(token.Struct)
+	p.want(token.Lbrace)
+	for p.Token() != token.EOF && p.Token() != token.Rbrace {
+		p.fieldDecl(typ)
+		// ";" is optional before "}"
+		if !p.got(token.Semi) && p.Token() != token.Rbrace {
+			p.syntaxError("in struct type; possibly missing semicolon or newline or }")
+			p.advance(token.Semi, token.Rbrace)
+			p.got(token.Semi)
+		}
+	}
+	typ.Rbrace = p.pos()
+	p.wantOrSync(token.Rbrace, token.Semi)
+
+	return typ
+}
+
+// FieldDecl      = IdentifierList Type | EmbeddedField .
+// EmbeddedField  = QualifiedName .
+func (p *parser) fieldDecl(styp *ast.StructType) {
+	if p.verbose {
+		defer p.trace("fieldDecl")()
+	}
+
+	pos := p.pos()
+	if p.Token() != token.Name {
+		p.syntaxError("expecting field name or embedded type")
+		p.advance(token.Semi, token.Rbrace)
+		return
+	}
+
+	name := p.name()
+	if p.Token() == token.Dot || p.Token() == token.Semi || p.Token() == token.Rbrace {
+		// EmbeddedField
+		var typ ast.Expr = name
+		if p.Token() == token.Dot {
+			t := new(ast.SelectorExpr)
+			t.Pos = p.pos()
+			p.Next()
+			t.X = name
+			t.Sel = p.name()
+			typ = t
+		}
+		p.addField(styp, pos, nil, typ)
+		return
+	}
+
+	// IdentifierList Type
+	names := p.nameList(name)
+	typ := p.typeOrNil()
+	if typ == nil {
+		typ = p.badExpr("expecting field type")
+	}
+	for _, name := range names {
+		p.addField(styp, name.Pos, name, typ)
+	}
+}
+
+// addField appends a field to styp. Fields declared together
+// share the same type, which lets the printer group them again.
+func (p *parser) addField(styp *ast.StructType, pos position.Pos, name *ast.Name, typ ast.Expr) {
+	f := new(ast.Field)
+	f.Pos = pos
+	f.Name = name
+	f.Type = typ
+	styp.FieldList = append(styp.FieldList, f)
+}
+
+// QualifiedName = Name [ "." Name ] .
+func (p *parser) qualifiedName() ast.Expr {
+	var x ast.Expr = p.name()
+	if p.Token() == token.Dot {
+		t := new(ast.SelectorExpr)
+		t.Pos = p.pos()
+		p.Next()
+		t.X = x
+		if p.Token() == token.Name {
+			t.Sel = p.name()
+		} else {
+			p.syntaxError("expecting name")
+		}
+		x = t
+	}
+	return x
+}
+
 func (p *parser) literal() *ast.BasicLit {
 	if p.Token() == token.Literal {
 		b := new(ast.BasicLit)
@@ -803,11 +1312,14 @@ recv:
 		goto recv
 	}
 	param.Type = name
-	p.want(token.Rparen)
+	p.wantOrSync(token.Rparen, token.Name, token.Lbrace)
 	return param
 }
 
-func (p *parser) paramlist() []*ast.Field {
+// paramlist parses the parameters following a "(" up to and including
+// the closing ")", whose position it returns; the position is unknown
+// if the list is malformed.
+func (p *parser) paramlist() (_ []*ast.Field, rparen position.Pos) {
 	list := make([]*ast.Field, 0)
 	none := "none"
 	str := " "
@@ -817,54 +1329,91 @@ redo:
 	case token.Name:
 		none = ""
 		param.Name = p.name()
-		if p.Token() == token.Name {
-			ptype := p.typeOrNil()
-			str += none + param.Name.Value + "(" + ptype.(*ast.Name).Value + ") "
+		if ptype := p.paramTypeOrNil(); ptype != nil {
+			str += none + param.Name.Value + "(" + String(ptype) + ") "
 			param.Type = ptype
+			p.checkDuplicate(list, param.Name)
 			list = append(list, param)
 			switch p.Token() {
 			case token.Comma:
+				if _, ok := ptype.(*ast.DotsType); ok {
+					p.syntaxErrorAt(ptype.GetPos(), "can only use ... with final parameter in list")
+				}
 				p.Next()
 				goto redo
 			case token.Rparen:
+				rparen = p.pos()
 				p.Next()
 				p.print("params:" + str)
-				return list
+				return list, rparen
 			default:
 				p.syntaxError("expecting comma or ')'")
 				p.Next()
-				return nil
+				return nil, rparen
 			}
 		} else {
 			p.syntaxError("expecting type")
 			p.Next()
-			return nil
+			return nil, rparen
 		}
 	case token.Rparen:
+		rparen = p.pos()
 		p.Next()
-		return nil
+		return nil, rparen
 	default:
 		p.syntaxError("expecting parameter or ')'")
 		p.Next()
-		return nil
+		return nil, rparen
 	}
 }
 
-func (p *parser) argList() []ast.Expr {
+// checkDuplicate reports name if a parameter in list has the same name.
+// Blank names may repeat.
+func (p *parser) checkDuplicate(list []*ast.Field, name *ast.Name) {
+	if name == nil || name.Value == "_" {
+		return
+	}
+	for _, f := range list {
+		if f != nil && f.Name != nil && f.Name.Value == name.Value {
+			p.errorAt(name.Pos, "duplicate parameter "+name.Value)
+			return
+		}
+	}
+}
+
+// ParameterType = [ "..." ] Type .
+func (p *parser) paramTypeOrNil() ast.Expr {
+	if p.Token() != token.DotDotDot {
+		return p.typeOrNil()
+	}
+	t := new(ast.DotsType)
+	t.Pos = p.pos()
+	p.Next()
+	t.Elem = p.typeOrNil()
+	if t.Elem == nil {
+		t.Elem = p.badExpr("... is missing type")
+	}
+	return t
+}
+
+func (p *parser) argList() (_ []ast.Expr, rparen position.Pos) {
 	if p.verbose {
 		defer p.trace("argList")()
 	}
 	list := make([]ast.Expr, 0)
 	p.want(token.Lparen)
-	if !p.got(token.Rparen) {
+	p.xnest++
+	for p.Token() != token.EOF && p.Token() != token.Rparen {
 		list = append(list, p.expr())
-		for !p.got(token.Rparen) {
-			p.want(token.Comma)
-			list = append(list, p.expr())
+		if !p.got(token.Comma) {
+			break
 		}
 	}
+	p.xnest--
+	rparen = p.pos()
+	p.wantOrSync(token.Rparen, token.Semi, token.Rbrace)
 
-	return list
+	return list, rparen
 }
 
 // ----------------------------------------------------------------------------
@@ -912,10 +1461,12 @@ func (p *parser) forStmt() ast.Stmt {
 
 func (p *parser) header(keyword token.Token) (init ast.SimpleStmt, cond ast.Expr, post ast.SimpleStmt) {
 	p.want(keyword)
+	outer := p.xnest
+	p.xnest = -1
+	defer func() { p.xnest = outer }()
 	if p.Token() == token.Lbrace {
 		if keyword == token.If {
-			p.syntaxError("missing condition in if statement")
-			cond = p.badExpr()
+			cond = p.badExpr("missing condition in if statement")
 		}
 		return
 	}
@@ -968,27 +1519,30 @@ done:
 	switch s := condStmt.(type) {
 	case nil:
 		if keyword == token.If && semi.pos.IsKnown() {
+			msg := "missing condition in if statement"
 			if semi.lit != "semicolon" {
-				p.syntaxErrorAt(semi.pos, fmt.Sprintf("unexpected %s, expecting { after if clause", semi.lit))
-			} else {
-				p.syntaxErrorAt(semi.pos, "missing condition in if statement")
+				msg = fmt.Sprintf("unexpected %s, expecting { after if clause", semi.lit)
 			}
-			b := new(ast.BadExpr)
-			b.Pos = semi.pos
-			cond = b
+			p.syntaxErrorAt(semi.pos, msg)
+			cond = ast.NewBadExpr(semi.pos, msg)
 		}
 	case *ast.ExprStmt:
 		cond = s.X
 	default:
+		if a, _ := s.(*ast.AssignStmt); a != nil && a.Op == token.NoneOp && a.Rhs != nil {
+			p.syntaxErrorAt(a.Pos, "assignment is not a condition; did you mean '=='?")
+			break
+		}
 		p.syntaxErrorAt(s.GetPos(), fmt.Sprintf("cannot use %s as value", s))
 	}
 	return
 }
 
-func (p *parser) badExpr() *ast.BadExpr {
-	b := new(ast.BadExpr)
-	b.Pos = p.pos()
-	return b
+// badExpr reports a syntax error with msg at the current token and
+// returns a bad expression there, with msg as its reason.
+func (p *parser) badExpr(msg string) *ast.BadExpr {
+	p.syntaxError(msg)
+	return ast.NewBadExpr(p.pos(), msg)
 }
 
 func (p *parser) ifStmt() *ast.IfStmt {
@@ -1012,13 +1566,92 @@ func (p *parser) ifStmt() *ast.IfStmt {
 	return s
 }
 
+// LabeledStmt = Label ":" Statement .
+// Label       = identifier .
+func (p *parser) labeledStmtOrNil(label *ast.Name) ast.Stmt {
+	if p.verbose {
+		defer p.trace("labeledStmt")()
+	}
+	s := new(ast.LabeledStmt)
+	s.Pos = p.pos()
+	s.Label = label
+	p.want(token.Colon)
+
+	if p.Token() == token.Rbrace {
+		// a statement is expected, but the semicolon of an
+		// empty statement may be omitted before a closing "}"
+		e := new(ast.EmptyStmt)
+		e.Pos = p.pos()
+		s.Stmt = e
+		return s
+	}
+
+	s.Stmt = p.stmtOrNil()
+	if s.Stmt != nil {
+		return s
+	}
+	p.syntaxErrorAt(s.Pos, "missing statement after label")
+	return nil
+}
+
+// SwitchStmt = "switch" [ SimpleStmt ";" ] [ Expression ] "{" { CaseClause } "}" .
+func (p *parser) switchStmt() *ast.SwitchStmt {
+	if p.verbose {
+		defer p.trace("switchStmt")()
+	}
+	s := new(ast.SwitchStmt)
+	s.Pos = p.pos()
+	s.Init, s.Tag, _ = p.header(token.Switch)
+	if !p.got(token.Lbrace) {
+		p.syntaxError("missing { after switch clause")
+		p.advance(token.Case, token.Default, token.Rbrace)
+	}
+	for p.Token() != token.EOF && p.Token() != token.Rbrace {
+		s.Body = append(s.Body, p.caseClause())
+	}
+	s.Rbrace = p.pos()
+	p.want(token.Rbrace)
+	return s
+}
+
+// CaseClause = ( "case" ExpressionList | "default" ) ":" StatementList .
+func (p *parser) caseClause() *ast.CaseClause {
+	if p.verbose {
+		defer p.trace("caseClause")()
+	}
+	c := new(ast.CaseClause)
+	c.Pos = p.pos()
+	switch p.Token() {
+	case token.Case:
+		p.Next()
+		c.CaseList = p.exprList()
+	case token.Default:
+		p.Next()
+	default:
+		p.syntaxError("expecting case or default or }")
+		p.advance(token.Colon, token.Case, token.Default, token.Rbrace)
+	}
+	c.Colon = p.pos()
+	p.want(token.Colon)
+	c.Body = p.stmtList()
+	return c
+}
+
 func (p *parser) whileStmt() ast.Stmt {
 	if p.verbose {
 		defer p.trace("whileStmt")()
 	}
 	s := new(ast.WhileStmt)
 	s.Pos = p.pos()
+	outer := p.xnest
+	p.xnest = -1
 	s.Cond = p.expr()
+	if p.Token() == token.Assign {
+		p.syntaxError("assignment is not a condition; did you mean '=='?")
+		p.Next()
+		p.expr()
+	}
+	p.xnest = outer
 	s.Body = p.blockStmt("While clause")
 	return s
 }
@@ -1047,6 +1680,9 @@ func (p *parser) sliceLit() ast.Expr {
 	l := new(ast.SliceLit)
 	l.Pos = p.pos()
 	p.Next()
+	// The length of a [...]T literal is left to be inferred from its
+	// elements by a later pass.
+	l.Dots = p.got(token.DotDotDot)
 	p.want(token.Rbrack)
 	l.ElemType = p.typeOrNil()
 	if l.ElemType == nil {
@@ -1055,13 +1691,16 @@ func (p *parser) sliceLit() ast.Expr {
 	}
 	p.want(token.Lbrace)
 	l.Elems = make([]ast.Expr, 0)
-	if !p.got(token.Rbrace) {
+	p.xnest++
+	for p.Token() != token.EOF && p.Token() != token.Rbrace {
 		l.Elems = append(l.Elems, p.expr())
-		for !p.got(token.Rbrace) {
-			p.want(token.Comma)
-			l.Elems = append(l.Elems, p.expr())
+		if !p.got(token.Comma) {
+			break
 		}
 	}
+	p.xnest--
+	l.Rbrace = p.pos()
+	p.wantOrSync(token.Rbrace, token.Semi)
 	return l
 }
 
@@ -1109,9 +1748,20 @@ func (p *parser) updateBase(pos position.Pos, tline, tcol uint, text string) {
 	p.base = position.NewLineBase(pos, filename, line, col)
 }
 
+// ImportSpec = [ PackageName ] ImportPath .
+// ImportPath = string_lit .
 func (p *parser) importDecl(group *ast.Group) ast.Decl {
+	if p.verbose {
+		defer p.trace("importDecl")()
+	}
+
 	decl := new(ast.ImportDecl)
+	decl.Pos = p.pos()
+	decl.Group = group
 
+	if p.Token() == token.Name {
+		decl.LocalName = p.name()
+	}
 	decl.Path = p.litOrNil()
 
 	if decl.Path == nil {
@@ -1123,7 +1773,6 @@ func (p *parser) importDecl(group *ast.Group) ast.Decl {
 		p.syntaxErrorAt(decl.Path.GetPos(), "import path must be a string")
 		decl.Path.Bad = true
 	}
-	p.want(token.Semi)
 	return decl
 }
 
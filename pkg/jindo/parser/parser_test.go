@@ -0,0 +1,907 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package parser
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/position"
+	"jindo/pkg/jindo/token"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// firstStmt returns the first statement in the body of the first
+// function declared in f.
+func firstStmt(t *testing.T, f *ast.File) ast.Stmt {
+	t.Helper()
+	fn, ok := f.DeclList[0].(*ast.FuncDecl)
+	if !ok || fn.Body == nil || len(fn.Body.StmtList) == 0 {
+		t.Fatalf("no statements in %v", f.DeclList[0])
+	}
+	return fn.Body.StmtList[0]
+}
+
+func TestOperatorContinuation(t *testing.T) {
+	// A line ending in a binary operator continues onto the next line.
+	f := parseString(t, "space main\nfunc f() {\n\tx = a +\n\t\tb\n}\n")
+	s := firstStmt(t, f).(*ast.AssignStmt)
+	if x, ok := s.Rhs[0].(*ast.Operation); !ok || x.Op != token.Add || x.Y == nil {
+		t.Errorf("got %s, want a single addition", String(s.Rhs[0]))
+	}
+
+	// A line ending in an operand terminates the statement.
+	var errs []error
+	f, _ = Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\tx = a\n\t\t+ b\n}\n"), func(err error) { errs = append(errs, err) })
+	s = firstStmt(t, f).(*ast.AssignStmt)
+	if _, ok := s.Rhs[0].(*ast.Name); !ok {
+		t.Errorf("got %s, want a name", String(s.Rhs[0]))
+	}
+	if len(errs) == 0 {
+		t.Error("expected a syntax error for operator-leading continuation line")
+	}
+}
+
+func TestFileDoc(t *testing.T) {
+	f := parseString(t, "// Unrelated.\n\n// Space main does things.\n/* More. */\nspace main\n// Not doc.\nfunc f() {}\n")
+	var got []string
+	for _, c := range f.Doc {
+		got = append(got, c.Text)
+	}
+	want := []string{"// Space main does things.", "/* More. */"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got doc %q, want %q", got, want)
+	}
+	if pos := f.Doc[0].Pos; pos.Line() != 3 || pos.Col() != 1 {
+		t.Errorf("got doc position %s, want line 3, column 1", pos)
+	}
+
+	f = parseString(t, "space main\n")
+	if f.Doc != nil {
+		t.Errorf("got doc %v, want none", f.Doc)
+	}
+}
+
+func TestMissingRparen(t *testing.T) {
+	var errs []error
+	f, _ := Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\tg(a\n\tb := 1\n}\n"), func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "3:5: syntax error: expected )") {
+		t.Fatalf("got errors %v, want a single missing ) at 3:5", errs)
+	}
+
+	// Parsing continues after the call.
+	fn := f.DeclList[0].(*ast.FuncDecl)
+	if len(fn.Body.StmtList) != 2 {
+		t.Fatalf("got %d statements, want 2", len(fn.Body.StmtList))
+	}
+	if _, ok := fn.Body.StmtList[1].(*ast.DefineStmt); !ok {
+		t.Errorf("got %T, want *ast.DefineStmt", fn.Body.StmtList[1])
+	}
+}
+
+func TestImportDecl(t *testing.T) {
+	const src = "space main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n)\n\nimport \"os\""
+	f := parseString(t, src)
+	if len(f.DeclList) != 3 {
+		t.Fatalf("got %d declarations, want 3", len(f.DeclList))
+	}
+	var paths []string
+	var groups []*ast.Group
+	for _, d := range f.DeclList {
+		imp := d.(*ast.ImportDecl)
+		if !imp.Pos.IsKnown() {
+			t.Errorf("import %s has no position", imp.Path.Value)
+		}
+		paths = append(paths, imp.Path.Value)
+		groups = append(groups, imp.Group)
+	}
+	if want := []string{`"fmt"`, `"io"`, `"os"`}; strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("got paths %v, want %v", paths, want)
+	}
+	if groups[0] == nil || groups[0] != groups[1] || groups[2] != nil {
+		t.Errorf("got groups %p, want a shared group for the first two imports only", groups)
+	}
+	verifyPrint(t, "test.jindo", f)
+
+	var errs []error
+	Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nimport 1\n"), func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "2:8: syntax error: import path must be a string") {
+		t.Errorf("got errors %v, want a non-string path error at 2:8", errs)
+	}
+}
+
+func TestVarDeclMismatch(t *testing.T) {
+	var errs []error
+	Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nvar a, b = 1\n"), func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "2:5: syntax error: assignment mismatch: 2 variables but 1 value") {
+		t.Errorf("got errors %v, want an assignment mismatch at 2:5", errs)
+	}
+}
+
+func TestContinueStmt(t *testing.T) {
+	for _, src := range []string{
+		"space main\n\nfunc f() {\n\tfor i > n {\n\t\tcontinue\n\t}\n}\n",
+		"space main\n\nfunc f() {\n\twhile i > n {\n\t\tif i > 2 {\n\t\t\tcontinue\n\t\t}\n\t\tbreak\n\t}\n}\n",
+	} {
+		f := parseString(t, src)
+		var found bool
+		ast.Inspect(f, func(n ast.Node) bool {
+			if s, ok := n.(*ast.ContinueStmt); ok {
+				found = true
+				if !s.Pos.IsKnown() {
+					t.Errorf("continue has no position")
+				}
+			}
+			return true
+		})
+		if !found {
+			t.Errorf("no continue statement in %q", src)
+		}
+
+		var buf strings.Builder
+		if _, err := Fprint(&buf, f, 0); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != src {
+			t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+		}
+	}
+}
+
+func TestMultiAssign(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\ta, b = b, a\n\tx, y := g()\n\tc, d := 1, 2\n\te += 1\n}\n"
+	f := parseString(t, src)
+	a := firstStmt(t, f).(*ast.AssignStmt)
+	if len(a.Lhs) != 2 || len(a.Rhs) != 2 {
+		t.Errorf("got %d = %d expressions, want 2 = 2", len(a.Lhs), len(a.Rhs))
+	}
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+
+	for _, test := range []struct {
+		stmt, want string
+	}{
+		{"a, b = 1", "3:7: syntax error: assignment mismatch: 2 variables but 1 value"},
+		{"x := 1, 2", "3:4: syntax error: assignment mismatch: 1 variable but 2 values"},
+		{"a, b += 1, 2", "3:7: syntax error: assignment operation += requires single-valued expressions"},
+	} {
+		var errs []error
+		Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\t"+test.stmt+"\n}\n"), func(err error) { errs = append(errs, err) })
+		if len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), test.want) {
+			t.Errorf("%s: got errors %v, want %s", test.stmt, errs, test.want)
+		}
+	}
+}
+
+func TestParenExpr(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\tx = (a + b) * c\n\ty = (g)(1)\n}\n"
+	f := parseString(t, src)
+	s := firstStmt(t, f).(*ast.AssignStmt)
+	mul, ok := s.Rhs[0].(*ast.Operation)
+	if !ok || mul.Op != token.Mul {
+		t.Fatalf("got %s, want a multiplication", String(s.Rhs[0]))
+	}
+	if _, ok := mul.X.(*ast.ParenExpr); !ok {
+		t.Errorf("got %T, want *ast.ParenExpr", mul.X)
+	}
+
+	call := f.DeclList[0].(*ast.FuncDecl).Body.StmtList[1].(*ast.AssignStmt).Rhs[0].(*ast.CallExpr)
+	if _, ok := call.Func.(*ast.ParenExpr); !ok || len(call.ArgList) != 1 {
+		t.Errorf("got %s, want a call of a parenthesized function", String(call))
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+}
+
+func TestLoopBodyNotNil(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\twhile c {}\n}\n")
+	if w := firstStmt(t, f).(*ast.WhileStmt); w.Body == nil || len(w.Body.StmtList) != 0 {
+		t.Errorf("got body %v, want an empty block", w.Body)
+	}
+
+	for _, src := range []string{
+		"space main\nfunc f() {\n\twhile c \n}\n",
+		"space main\nfunc f() {\n\tfor c \n}\n",
+	} {
+		var errs []error
+		f, _ := Parse(position.NewFileBase("test.jindo"), strings.NewReader(src), func(err error) { errs = append(errs, err) })
+		if len(errs) == 0 {
+			t.Errorf("%q: got no errors, want a missing body", src)
+		}
+		switch s := firstStmt(t, f).(type) {
+		case *ast.WhileStmt:
+			if s.Body == nil {
+				t.Errorf("%q: got nil body", src)
+			}
+		case *ast.ForStmt:
+			if s.Body == nil {
+				t.Errorf("%q: got nil body", src)
+			}
+		default:
+			t.Errorf("%q: got %T, want a loop", src, s)
+		}
+	}
+}
+
+func TestMissingSpace(t *testing.T) {
+	var errs []error
+	f, first := Parse(position.NewFileBase("test.jindo"), strings.NewReader("func f() {}\nfunc g() {}\n"), func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "1:1: syntax error: space clause must be first") {
+		t.Errorf("got errors %v, want a missing space clause at 1:1", errs)
+	}
+	if first == nil {
+		t.Error("got no first error")
+	}
+	if f == nil || f.SpaceName != nil || len(f.DeclList) != 2 {
+		t.Fatalf("got %v, want a partial file with two declarations", f)
+	}
+	if got, want := String(f), "func f() {}; func g() {}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	const src = "space main\nfunc f() {\n\tg(a\n\tb := (1\n\th(a b)\n}\n"
+	f, errs := ParseAll(position.NewFileBase("test.jindo"), strings.NewReader(src), 0)
+	if f == nil {
+		t.Fatal("got no file")
+	}
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Pos.String())
+	}
+	if want := "[test.jindo:3:5 test.jindo:4:9 test.jindo:5:6]"; fmt.Sprint(got) != want {
+		t.Errorf("got errors at %v, want %s", got, want)
+	}
+
+	// stop after the first two errors
+	f, errs = ParseAll(position.NewFileBase("test.jindo"), strings.NewReader(src), 2)
+	if f != nil || len(errs) != 2 {
+		t.Errorf("got %d errors and file %v, want 2 errors and no file", len(errs), f)
+	}
+	if errs.Err() == nil {
+		t.Error("got nil Err for a non-empty list")
+	}
+}
+
+func TestErrorListSort(t *testing.T) {
+	a := position.NewFileBase("a.jindo")
+	b := position.NewFileBase("b.jindo")
+	var l ErrorList
+	l.Add(position.MakePos(b, 1, 1), "b1")
+	l.Add(position.MakePos(a, 2, 1), "a2")
+	l.Add(position.MakePos(a, 1, 5), "a1:5")
+	l.Add(position.MakePos(a, 1, 2), "a1:2")
+	l.Sort()
+	var got []string
+	for _, err := range l {
+		got = append(got, err.Msg)
+	}
+	if want := "[a1:2 a1:5 a2 b1]"; fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+// TestConcurrentParse parses files in parallel into a shared FileSet
+// and ErrorList; run it with -race to check their synchronization.
+func TestConcurrentParse(t *testing.T) {
+	const n = 8
+	fset := position.NewFileSet()
+	var errs ErrorList
+	var mu sync.Mutex // guards errs
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			base := fset.AddFile(fmt.Sprintf("f%d.jindo", i))
+			src := fmt.Sprintf("space main\nfunc f%d() {\n\tg(a\n}\n", i)
+			Parse(base, strings.NewReader(src), func(err error) {
+				if err, ok := err.(Error); ok {
+					mu.Lock()
+					errs.Add(err.Pos, err.Msg)
+					mu.Unlock()
+				}
+			})
+			fset.Cmp(position.MakePos(base, 1, 1), position.MakePos(base, 2, 1))
+		}(i)
+	}
+	wg.Wait()
+
+	if len(fset.Files()) != n {
+		t.Fatalf("got %d files, want %d", len(fset.Files()), n)
+	}
+	if len(errs) != n {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), n, errs)
+	}
+	var pos []position.Pos
+	for _, err := range errs {
+		pos = append(pos, err.Pos)
+	}
+	fset.Sort(pos)
+	for i, p := range pos {
+		if !strings.HasPrefix(p.String(), fset.Files()[i].Filename()+":") {
+			t.Errorf("error %d: got %s, want an error in %s", i, p, fset.Files()[i].Filename())
+		}
+	}
+}
+
+func TestCompositeLit(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\tp = Point{x: 1, y: 2}\n\tq = geo.Point{1, 2}\n\tr = Point{}\n\tif p == (Point{}) {}\n\tif x {}\n\twhile g(Point{}) {}\n}\n"
+	f := parseString(t, src)
+	body := f.DeclList[0].(*ast.FuncDecl).Body.StmtList
+
+	for i, want := range []struct {
+		typ         string
+		elems, keys int
+	}{
+		{"Point", 2, 2},
+		{"geo.Point", 2, 0},
+		{"Point", 0, 0},
+	} {
+		lit, ok := body[i].(*ast.AssignStmt).Rhs[0].(*ast.CompositeLit)
+		if !ok {
+			t.Errorf("stmt %d: got %s, want a composite literal", i, String(body[i]))
+			continue
+		}
+		if String(lit.Type) != want.typ || len(lit.ElemList) != want.elems || lit.NKeys != want.keys {
+			t.Errorf("stmt %d: got %s with %d elements and %d keys, want %s with %d and %d", i, String(lit.Type), len(lit.ElemList), lit.NKeys, want.typ, want.elems, want.keys)
+		}
+	}
+
+	// the "{" after a statement header opens the block
+	if s := body[4].(*ast.IfStmt); String(s.Cond) != "x" || s.Block == nil {
+		t.Errorf("got if condition %s, want x", String(s.Cond))
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+}
+
+func TestSwitchStmt(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\tswitch x {\n\tcase 1, 2:\n\t\ty = 1\n\tcase 3:\n\tdefault:\n\t\ty = 2\n\t\tbreak\n\t}\n\tswitch {\n\tcase x > 1:\n\t\tg(Point{})\n\t}\n\tswitch x := g(); x {}\n}\n"
+	f := parseString(t, src)
+	body := f.DeclList[0].(*ast.FuncDecl).Body.StmtList
+
+	s := body[0].(*ast.SwitchStmt)
+	if String(s.Tag) != "x" || s.Init != nil || len(s.Body) != 3 {
+		t.Fatalf("got switch %s with %d clauses, want switch x with 3", String(s.Tag), len(s.Body))
+	}
+	for i, want := range []struct{ cases, stmts int }{{2, 1}, {1, 0}, {0, 2}} {
+		c := s.Body[i]
+		if len(c.CaseList) != want.cases || len(c.Body) != want.stmts {
+			t.Errorf("clause %d: got %d cases and %d statements, want %d and %d", i, len(c.CaseList), len(c.Body), want.cases, want.stmts)
+		}
+	}
+	if s.Body[2].CaseList != nil {
+		t.Errorf("got cases %v, want a default clause", s.Body[2].CaseList)
+	}
+
+	if s := body[1].(*ast.SwitchStmt); s.Tag != nil || len(s.Body) != 1 {
+		t.Errorf("got switch %s with %d clauses, want a tagless switch with 1", String(s.Tag), len(s.Body))
+	}
+	if s := body[2].(*ast.SwitchStmt); s.Init == nil || String(s.Tag) != "x" {
+		t.Errorf("got switch init %v and tag %s, want an init and tag x", s.Init, String(s.Tag))
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+
+	var errs []error
+	Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\tswitch x {\n\tx = 1\n\t}\n}\n"), func(err error) { errs = append(errs, err) })
+	if len(errs) == 0 || !strings.HasSuffix(errs[0].Error(), "4:2: syntax error: unexpected x, expecting case or default or }") {
+		t.Errorf("got errors %v, want a missing case", errs)
+	}
+}
+
+func TestLabeledStmt(t *testing.T) {
+	const src = "space main\n\nfunc f() {\nOuter:\n\tfor {\n\t\tfor x > 0 {\n\t\t\tif x == 1 {\n\t\t\t\tcontinue Outer\n\t\t\t}\n\t\t\tbreak Outer\n\t\t}\n\t\tx := 1\n\t\tbreak\n\t}\n\tgoto Outer\n}\n"
+	f := parseString(t, src)
+	body := f.DeclList[0].(*ast.FuncDecl).Body.StmtList
+
+	l, ok := body[0].(*ast.LabeledStmt)
+	if !ok || l.Label.Value != "Outer" {
+		t.Fatalf("got %T, want a statement labeled Outer", body[0])
+	}
+	outer, ok := l.Stmt.(*ast.ForStmt)
+	if !ok {
+		t.Fatalf("got labeled %T, want a for statement", l.Stmt)
+	}
+	inner := outer.Body.StmtList[0].(*ast.ForStmt).Body.StmtList
+	if c := inner[0].(*ast.IfStmt).Block.StmtList[0].(*ast.ContinueStmt); c.Label == nil || c.Label.Value != "Outer" {
+		t.Errorf("got continue label %v, want Outer", c.Label)
+	}
+	if b := inner[1].(*ast.BreakStmt); b.Label == nil || b.Label.Value != "Outer" {
+		t.Errorf("got break label %v, want Outer", b.Label)
+	}
+	if _, ok := outer.Body.StmtList[1].(*ast.DefineStmt); !ok {
+		t.Errorf("got %T, want := to still define", outer.Body.StmtList[1])
+	}
+	if b := outer.Body.StmtList[2].(*ast.BreakStmt); b.Label != nil {
+		t.Errorf("got break label %v, want none", b.Label)
+	}
+	if g, ok := body[1].(*ast.GotoStmt); !ok || g.Label.Value != "Outer" {
+		t.Errorf("got %s, want goto Outer", String(body[1]))
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+}
+
+func TestVariadicParams(t *testing.T) {
+	f := parseString(t, "space main\nfunc printf(fmt string, args ...int) {}\n")
+	params := f.DeclList[0].(*ast.FuncDecl).Param
+	if len(params) != 2 {
+		t.Fatalf("got %d parameters, want 2", len(params))
+	}
+	if _, ok := params[0].Type.(*ast.DotsType); ok {
+		t.Errorf("got variadic %s, want string", String(params[0].Type))
+	}
+	if dots, ok := params[1].Type.(*ast.DotsType); !ok || String(dots.Elem) != "int" {
+		t.Errorf("got %s, want ...int", String(params[1].Type))
+	}
+
+	for _, test := range []struct {
+		params, want string
+	}{
+		{"a ...int, b int, c int", "2:10: syntax error: can only use ... with final parameter in list"},
+		{"a int, b ...int, c int", "2:17: syntax error: can only use ... with final parameter in list"},
+		{"a ...", "2:13: syntax error: ... is missing type"},
+	} {
+		var errs []error
+		Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f("+test.params+") {}\n"), func(err error) { errs = append(errs, err) })
+		if len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), test.want) {
+			t.Errorf("%s: got errors %v, want %s", test.params, errs, test.want)
+		}
+	}
+}
+
+func TestOperNames(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		op   token.Operator
+	}{
+		{"not", token.Not},
+		{"add", token.Add},
+		{"sub", token.Sub},
+		{"mul", token.Mul},
+		{"div", token.Div},
+		{"eql", token.Eql},
+		{"gtr", token.Gtr},
+		{"rem", token.Rem},
+		{"rnot", token.Not | token.Reverse},
+		{"radd", token.Add | token.Reverse},
+		{"rsub", token.Sub | token.Reverse},
+		{"rmul", token.Mul | token.Reverse},
+		{"rdiv", token.Div | token.Reverse},
+		{"reql", token.Eql | token.Reverse},
+		{"rgtr", token.Gtr | token.Reverse},
+		{"rrem", token.Rem | token.Reverse},
+	} {
+		f := parseString(t, "space main\noper (a Vec) "+test.name+" (b Vec) Vec {\n\treturn a\n}\n")
+		d, ok := f.DeclList[0].(*ast.OperDecl)
+		if !ok {
+			t.Errorf("%s: got %T, want *ast.OperDecl", test.name, f.DeclList[0])
+			continue
+		}
+		if d.Oper != test.op || d.Oper.OverloadName() != test.name {
+			t.Errorf("%s: got operator %d (%s), want %d", test.name, d.Oper, d.Oper.OverloadName(), test.op)
+		}
+	}
+
+	for _, name := range []string{"foo", "and", "radd2", "Add"} {
+		var errs []error
+		Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\noper (a Vec) "+name+" (b Vec) Vec {}\n"), func(err error) { errs = append(errs, err) })
+		if want := "2:14: syntax error: unknown operator name " + name; len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), want) {
+			t.Errorf("%s: got errors %v, want %s", name, errs, want)
+		}
+	}
+
+	// malformed operands or return types are reported once, not dereferenced
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"oper add(x T) T {}", "2:6: syntax error: unexpected add, expecting '('"},
+		{"oper (a T) add x T) T {}", "2:16: syntax error: unexpected x, expecting '('"},
+		{"oper (a T) add x T) T {\n\treturn a\n}\nfunc f() {}", "2:16: syntax error: unexpected x, expecting '('"},
+		{"oper (a T) add (b T) {\n\treturn a\n}\nfunc f() {}", "2:22: expecting type"},
+	} {
+		var errs []error
+		f, _ := Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\n"+test.src+"\n"), func(err error) { errs = append(errs, err) })
+		if len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), test.want) {
+			t.Errorf("%q: got errors %v, want %s", test.src, errs, test.want)
+		}
+		if f != nil && len(f.DeclList) > 1 {
+			t.Errorf("%q: got %d declarations, want at most 1", test.src, len(f.DeclList))
+		}
+	}
+}
+
+func TestSliceExprErrors(t *testing.T) {
+	for _, test := range []struct {
+		expr, want string
+	}{
+		{"s[lo::max]", "3:11: syntax error: middle index required in 3-index slice"},
+		{"s[lo:hi:]", "3:14: syntax error: final index required in 3-index slice"},
+		{"s[lo", "3:10: syntax error: expected ], got ;"},
+	} {
+		var errs []error
+		Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\ta = "+test.expr+"\n}\n"), func(err error) { errs = append(errs, err) })
+		if len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), test.want) {
+			t.Errorf("%s: got errors %v, want %s", test.expr, errs, test.want)
+		}
+	}
+}
+
+func TestComparisonOrder(t *testing.T) {
+	// tree renders x with every binary operation parenthesized.
+	var tree func(x ast.Expr) string
+	tree = func(x ast.Expr) string {
+		switch x := x.(type) {
+		case *ast.Name:
+			return x.Value
+		case *ast.Operation:
+			if x.Y != nil {
+				return "(" + tree(x.X) + " " + x.Op.String() + " " + tree(x.Y) + ")"
+			}
+		}
+		return fmt.Sprintf("%T", x)
+	}
+
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"a < b", "(a < b)"},
+		{"b > a", "(b > a)"},
+		{"a < b < c", "((a < b) < c)"},
+		{"a > b < c", "((a > b) < c)"},
+		{"a < b + c", "(a < (b + c))"},
+	} {
+		list, err := ParseExprList(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		if got := tree(list[0]); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.src, got, test.want)
+		}
+	}
+}
+
+func TestElseIfPos(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\tif a {\n\t} else if b {\n\t} else {\n\t}\n}\n")
+	s := f.DeclList[0].(*ast.FuncDecl).Body.StmtList[0].(*ast.IfStmt)
+	elif, ok := s.Else.(*ast.IfStmt)
+	if !ok {
+		t.Fatalf("got else %T, want *ast.IfStmt", s.Else)
+	}
+	for _, test := range []struct {
+		what      string
+		pos       position.Pos
+		line, col uint
+	}{
+		{"if", s.Pos, 3, 2},
+		{"else if", elif.Pos, 4, 9},
+		{"else if condition", elif.Cond.GetPos(), 4, 12},
+	} {
+		if test.pos.Line() != test.line || test.pos.Col() != test.col {
+			t.Errorf("%s: got %d:%d, want %d:%d", test.what, test.pos.Line(), test.pos.Col(), test.line, test.col)
+		}
+	}
+	if _, ok := elif.Else.(*ast.BlockStmt); !ok {
+		t.Errorf("got final else %T, want *ast.BlockStmt", elif.Else)
+	}
+
+	var errs []error
+	Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\tif a {\n\t} else b\n}\n"), func(err error) { errs = append(errs, err) })
+	if want := "4:9: syntax error: else must be followed by if or statement block"; len(errs) == 0 || !strings.HasSuffix(errs[0].Error(), want) {
+		t.Errorf("got errors %v, want %s", errs, want)
+	}
+}
+
+func TestPredeclaredNames(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\tx = true\n\tx = false\n\tx = nil\n\tx = truth\n\ttrue = 1\n}\n")
+	for i, want := range []ast.NameKind{ast.TrueName, ast.FalseName, ast.NilName, ast.PlainName} {
+		s := f.DeclList[0].(*ast.FuncDecl).Body.StmtList[i].(*ast.AssignStmt)
+		n := s.Rhs[0].(*ast.Name)
+		if n.Kind != want || n.Kind.IsPredeclared() != (want != ast.PlainName) {
+			t.Errorf("%s: got kind %d, want %d", n.Value, n.Kind, want)
+		}
+		if lhs := s.Lhs[0].(*ast.Name); lhs.Kind.IsPredeclared() {
+			t.Errorf("%s: got predeclared kind %d", lhs.Value, lhs.Kind)
+		}
+	}
+
+	// assignments to a predeclared name still parse, flagged
+	s := f.DeclList[0].(*ast.FuncDecl).Body.StmtList[4].(*ast.AssignStmt)
+	if n := s.Lhs[0].(*ast.Name); n.Kind != ast.TrueName {
+		t.Errorf("got kind %d for assigned %s, want %d", n.Kind, n.Value, ast.TrueName)
+	}
+}
+
+func TestAssignmentCondition(t *testing.T) {
+	const want = "syntax error: assignment is not a condition; did you mean '=='?"
+	for _, test := range []struct {
+		stmt string
+		col  int
+	}{
+		{"if x = 1 {\n\t}", 7},
+		{"if y := 0; x = 1 {\n\t}", 15},
+		{"while x = 1 {\n\t}", 10},
+		{"for i := 0; i = 10; i = i + 1 {\n\t}", 16},
+	} {
+		var errs []error
+		Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\t"+test.stmt+"\n}\n"), func(err error) { errs = append(errs, err) })
+		if want := fmt.Sprintf("test.jindo:3:%d: %s", test.col, want); len(errs) != 1 || errs[0].Error() != want {
+			t.Errorf("%s: got errors %v, want %s", test.stmt, errs, want)
+		}
+	}
+
+	// comparisons and other simple statements are unaffected
+	parseString(t, "space main\nfunc f() {\n\tif x == 1 {\n\t}\n\twhile x == 1 {\n\t}\n}\n")
+	var errs []error
+	Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\tif x += 1 {\n\t}\n}\n"), func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "as value") {
+		t.Errorf("got errors %v, want cannot use ... as value", errs)
+	}
+}
+
+func TestIncDecStmt(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\ti++\n\tp.n--\n\tfor i := 0; i < 10; i++ {\n\t}\n}\n")
+	body := f.DeclList[0].(*ast.FuncDecl).Body.StmtList
+
+	for i, test := range []struct {
+		x   string
+		op  token.Operator
+		col int
+	}{
+		{"i", token.Add, 3},
+		{"p.n", token.Sub, 5},
+	} {
+		s, ok := body[i].(*ast.IncDecStmt)
+		if !ok {
+			t.Errorf("stmt %d: got %T, want *ast.IncDecStmt", i, body[i])
+			continue
+		}
+		if got := String(s.X); got != test.x || s.Op != test.op {
+			t.Errorf("stmt %d: got %s with %s, want %s with %s", i, got, s.Op, test.x, test.op)
+		}
+		if got := s.GetPos().Col(); got != uint(test.col) {
+			t.Errorf("stmt %d: got column %d, want %d", i, got, test.col)
+		}
+	}
+
+	post, ok := body[2].(*ast.ForStmt).Post.(*ast.IncDecStmt)
+	if !ok || post.Op != token.Add {
+		t.Errorf("got post statement %#v, want i++", body[2].(*ast.ForStmt).Post)
+	}
+
+	var errs []error
+	Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f() {\n\ti, j++\n}\n"), func(err error) { errs = append(errs, err) })
+	if want := "test.jindo:3:6: syntax error: unexpected ++, expecting := or = or comma"; len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("got errors %v, want %s", errs, want)
+	}
+}
+
+func TestParseExprList(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want []string // printed elements
+		err  string   // suffix of the first error, if any
+	}{
+		{"1", []string{"1"}, ""},
+		{"1, 2, f(x)", []string{"1", "2", "f(x)"}, ""},
+		{"a + b, s[i]\n", []string{"a + b", "s[i]"}, ""},
+		{"1, 2,", []string{"1", "2", "<bad expr>"}, "1:6: syntax error: unexpected fileOrEof, expecting expression"},
+		{"1, (2 +), 3", []string{"1", "<bad expr>", "3"}, "1:8: syntax error: unexpected ), expecting expression"},
+		{"1, 2 x", []string{"1", "2"}, "1:6: syntax error: expected fileOrEof, got name"},
+		{"1; 2", []string{"1"}, "1:2: syntax error: expected fileOrEof, got ;"},
+	} {
+		list, err := ParseExprList(test.src)
+		var got []string
+		for _, x := range list {
+			got = append(got, String(x))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+		}
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", test.src, err)
+		case test.err != "" && (err == nil || !strings.HasSuffix(err.Error(), test.err)):
+			t.Errorf("%q: got error %v, want %s", test.src, err, test.err)
+		}
+	}
+}
+
+func TestBadExprReason(t *testing.T) {
+	for _, test := range []struct {
+		src, reason string
+	}{
+		{"space main\nvar m map[]int\n", "missing key type in map type"},
+		{"space main\nvar p *\n", "missing element type in pointer type"},
+		{"space main\nconst c int\n", "expecting = in const declaration"},
+		{"space main\nfunc f() {\n\tx = 1 + ]\n}\n", "expecting expression"},
+	} {
+		var errs []error
+		f, _ := Parse(position.NewFileBase("test.jindo"), strings.NewReader(test.src), func(err error) { errs = append(errs, err) })
+
+		var bad []*ast.BadExpr
+		ast.Inspect(f, func(n ast.Node) bool {
+			if x, ok := n.(*ast.BadExpr); ok {
+				bad = append(bad, x)
+			}
+			return true
+		})
+		if len(bad) == 0 || len(errs) == 0 {
+			t.Errorf("%q: got %d bad expressions and errors %v, want some", test.src, len(bad), errs)
+			continue
+		}
+		if got := bad[0].Reason(); got != test.reason || !strings.HasSuffix(errs[0].Error(), got) {
+			t.Errorf("%q: got reason %q for error %v, want %q", test.src, got, errs[0], test.reason)
+		}
+	}
+
+	list, _ := ParseExprList("1, (2 +), 3")
+	if got, want := list[1].(*ast.BadExpr).Reason(), "unexpected ), expecting expression"; got != want {
+		t.Errorf("ParseExprList: got reason %q, want %q", got, want)
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, list[1], DebugForm); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "<bad expr: unexpected ), expecting expression>"; got != want {
+		t.Errorf("DebugForm: got %s, want %s", got, want)
+	}
+	if got, want := String(list[1]), "<bad expr>"; got != want {
+		t.Errorf("ShortForm: got %s, want %s", got, want)
+	}
+}
+
+func TestLiteralKinds(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\tx = 'a' + 1 + 2.5 + 3i\n\ty = '\\n' * 0x1p-2i - 07i\n}\n")
+	var lits []*ast.BasicLit
+	ast.Inspect(f, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok {
+			lits = append(lits, lit)
+		}
+		return true
+	})
+	want := []struct {
+		value string
+		kind  token.LitKind
+	}{
+		{"'a'", token.RuneLit},
+		{"1", token.IntLit},
+		{"2.5", token.FloatLit},
+		{"3i", token.ImagLit},
+		{"'\\n'", token.RuneLit},
+		{"0x1p-2i", token.ImagLit},
+		{"07i", token.ImagLit},
+	}
+	if len(lits) != len(want) {
+		t.Fatalf("got %d literals, want %d", len(lits), len(want))
+	}
+	sort.Slice(lits, func(i, j int) bool { return lits[i].Pos.Cmp(lits[j].Pos) < 0 })
+	for i, lit := range lits {
+		if lit.Value != want[i].value || lit.Kind != want[i].kind {
+			t.Errorf("got %s of kind %d, want %s of kind %d", lit.Value, lit.Kind, want[i].value, want[i].kind)
+		}
+		if lit.IsRune() != (lit.Kind == token.RuneLit) || lit.IsImaginary() != (lit.Kind == token.ImagLit) {
+			t.Errorf("%s: got IsRune %v, IsImaginary %v", lit.Value, lit.IsRune(), lit.IsImaginary())
+		}
+	}
+}
+
+func TestDuplicateParams(t *testing.T) {
+	for _, test := range []struct {
+		decl, want string
+	}{
+		{"func f(x int, y int) {}", ""},
+		{"func f(_ int, _ int, x int) {}", ""},
+		{"func f(x int, y int, x int) {}", "test.jindo:2:22: duplicate parameter x"},
+		{"func f(x int, x ...int) {}", "test.jindo:2:15: duplicate parameter x"},
+		{"oper (a T) add (b T) T {\n\treturn a\n}", ""},
+		{"oper (_ T) add (_ T) T {\n\treturn a\n}", ""},
+		{"oper (a T) add (a T) T {\n\treturn a\n}", "test.jindo:2:17: duplicate parameter a"},
+	} {
+		var errs []string
+		Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\n"+test.decl+"\n"), func(err error) { errs = append(errs, err.Error()) })
+		var want []string
+		if test.want != "" {
+			want = append(want, test.want)
+		}
+		if fmt.Sprint(errs) != fmt.Sprint(want) {
+			t.Errorf("%s: got errors %v, want %v", test.decl, errs, want)
+		}
+	}
+}
+
+func TestParserReset(t *testing.T) {
+	var p Parser
+	for i, test := range []struct {
+		src, err string
+	}{
+		{"space main\nfunc f() {\n\tg(a\n}\n", "a.jindo:3:5: syntax error: expected ), got ;"},
+		{"space main\nfunc f() {\n\tx = 1\n}\n", ""},
+		{"space main\nfunc f() { x }\n", ""},
+	} {
+		name := string(rune('a'+i)) + ".jindo"
+		var errs []string
+		p.Reset(position.NewFileBase(name), strings.NewReader(test.src), func(err error) { errs = append(errs, err.Error()) })
+		f, err := p.Parse()
+		if f == nil || f.SpaceName.Value != "main" {
+			t.Errorf("%s: got no file", name)
+		}
+		// errors of earlier files must not carry over
+		if test.err == "" && (err != nil || len(errs) != 0) {
+			t.Errorf("%s: got errors %v, %v", name, err, errs)
+		}
+		if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%s: got error %v, want %s", name, err, test.err)
+		}
+	}
+
+	// without an error handler, Parse stops at the first error
+	p.Reset(position.NewFileBase("d.jindo"), strings.NewReader("space main\nfunc f() {\n\tg(a\n}\n"), nil)
+	if f, err := p.Parse(); f != nil || err == nil {
+		t.Errorf("got file %v and error %v, want only an error", f, err)
+	}
+}
+
+const benchSrc = "space main\n\nimport \"io\"\n\ntype T int\n\nfunc f(w io.Writer, n T) T {\n\tfor i := 0; i < n; i = i + 1 {\n\t\tg(w, i)\n\t}\n\treturn n\n}\n"
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	base := position.NewFileBase("bench.jindo")
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse(base, strings.NewReader(benchSrc), nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParserReset(b *testing.B) {
+	b.ReportAllocs()
+	base := position.NewFileBase("bench.jindo")
+	var p Parser
+	for i := 0; i < b.N; i++ {
+		p.Reset(base, strings.NewReader(benchSrc), nil)
+		if _, err := p.Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
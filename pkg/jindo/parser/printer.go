@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/position"
 	"jindo/pkg/jindo/token"
 	"strings"
 )
@@ -21,15 +22,29 @@ const (
 	_         Form = iota // default
 	LineForm              // use spaces instead of linebreaks where possible
 	ShortForm             // like LineForm but print "…" for non-empty function or composite literal bodies
+	DebugForm             // like the default form but print why each bad expression failed to parse
 )
 
 // Fprint prints node x to w in the specified form.
 // It returns the number of bytes written, and whether there was an error.
 func Fprint(w io.Writer, x ast.Node, form Form) (n int, err error) {
+	return fprint(w, x, form, false)
+}
+
+// FprintLines is like Fprint, but it precedes each declaration which is
+// not part of a group by a //line directive for the declaration's position.
+// Positions in the printed source then refer to the sources the declarations
+// were taken from, as when a file is assembled from several others.
+func FprintLines(w io.Writer, x ast.Node, form Form) (n int, err error) {
+	return fprint(w, x, form, true)
+}
+
+func fprint(w io.Writer, x ast.Node, form Form, lines bool) (n int, err error) {
 	p := printer{
 		output:     w,
 		form:       form,
-		linebreaks: form == 0,
+		linebreaks: form == 0 || form == DebugForm,
+		lines:      lines,
 	}
 
 	defer func() {
@@ -80,6 +95,7 @@ type printer struct {
 	written    int // number of bytes written
 	form       Form
 	linebreaks bool // print linebreaks instead of semis
+	lines      bool // print line directives before declarations
 
 	indent  int // current indentation level
 	nlcount int // number of consecutive newlines
@@ -359,7 +375,11 @@ func (p *printer) printRawNode(n ast.Node) {
 
 	// expressions and types
 	case *ast.BadExpr:
-		p.print(token.Name, "<bad expr>")
+		if p.form == DebugForm && n.Reason() != "" {
+			p.print(token.Name, "<bad expr: "+n.Reason()+">")
+		} else {
+			p.print(token.Name, "<bad expr>")
+		}
 
 	case *ast.Name:
 		p.print(token.Name, n.Value) // token.Token.Name requires actual value following immediately
@@ -370,12 +390,72 @@ func (p *printer) printRawNode(n ast.Node) {
 	case *ast.ParenExpr:
 		p.print(token.Lparen, n.X, token.Rparen)
 
+	case *ast.ListExpr:
+		p.printExprList(n.ElemList)
+
+	case *ast.SliceLit:
+		p.print(token.Lbrack)
+		if n.Dots {
+			p.print(token.DotDotDot)
+		}
+		p.print(token.Rbrack, n.ElemType, token.Lbrace)
+		p.printLitElems(n.Elems, n.Rbrace)
+		p.print(token.Rbrace)
+
+	case *ast.CompositeLit:
+		p.print(n.Type, token.Lbrace)
+		p.printLitElems(n.ElemList, n.Rbrace)
+		p.print(token.Rbrace)
+
+	case *ast.KeyValueExpr:
+		p.print(n.Key, token.Colon, blank, n.Value)
+
+	case *ast.PointerType:
+		p.print(token.Star, n.Elem)
+
+	case *ast.DotsType:
+		p.print(token.DotDotDot, n.Elem)
+
+	case *ast.StructType:
+		p.print(token.Struct)
+		if len(n.FieldList) > 0 && p.linebreaks {
+			p.print(blank)
+		}
+		p.print(token.Lbrace)
+		if len(n.FieldList) > 0 {
+			if p.linebreaks {
+				p.print(newline, indent)
+				p.printFieldList(n.FieldList, nil, token.Semi)
+				p.print(outdent, newline)
+			} else {
+				p.printFieldList(n.FieldList, nil, token.Semi)
+			}
+		}
+		p.print(token.Rbrace)
+
 	case *ast.SelectorExpr:
 		p.print(n.X, token.Dot, n.Sel)
 
 	case *ast.IndexExpr:
 		p.print(n.X, token.Lbrack, n.Index, token.Rbrack)
 
+	case *ast.SliceExpr:
+		p.print(n.X, token.Lbrack)
+		if n.Low != nil {
+			p.print(n.Low)
+		}
+		p.print(token.Colon)
+		if n.High != nil {
+			p.print(n.High)
+		}
+		if n.Full {
+			p.print(token.Colon)
+			if n.Max != nil {
+				p.print(n.Max)
+			}
+		}
+		p.print(token.Rbrack)
+
 	case *ast.CallExpr:
 		p.print(n.Func, token.Lparen)
 		p.printExprList(n.ArgList)
@@ -399,6 +479,9 @@ func (p *printer) printRawNode(n ast.Node) {
 	case *ast.SliceType:
 		p.print(token.Lbrack, token.Rbrack, n.Elem)
 
+	case *ast.MapType:
+		p.print(token.Map, token.Lbrack, n.Key, token.Rbrack, n.Value)
+
 	// statements
 	case *ast.DeclStmt:
 		p.printDecl(n.DeclList)
@@ -410,16 +493,38 @@ func (p *printer) printRawNode(n ast.Node) {
 		p.print(n.X)
 
 	case *ast.AssignStmt:
-		p.print(n.Lhs)
-		if n.Rhs == nil {
-			// TODO(gri) This is going to break the mayCombine
-			//           check once we enable that again.
-			p.print(n.Op, n.Op) // ++ or --
-		} else {
-			p.print(blank, n.Op, token.Assign, blank)
-			p.print(n.Rhs)
+		p.printExprList(n.Lhs)
+		p.print(blank, n.Op, token.Assign, blank)
+		p.printExprList(n.Rhs)
+
+	case *ast.IncDecStmt:
+		// TODO(gri) This is going to break the mayCombine
+		//           check once we enable that again.
+		p.print(n.X, n.Op, n.Op) // ++ or --
+
+	case *ast.DefineStmt:
+		p.printExprList(n.Lhs)
+		p.print(blank, token.Define, blank)
+		p.printExprList(n.Rhs)
+
+	case *ast.BreakStmt:
+		p.print(token.Break)
+		if n.Label != nil {
+			p.print(blank, n.Label)
+		}
+
+	case *ast.ContinueStmt:
+		p.print(token.Continue)
+		if n.Label != nil {
+			p.print(blank, n.Label)
 		}
 
+	case *ast.GotoStmt:
+		p.print(token.Goto, blank, n.Label)
+
+	case *ast.LabeledStmt:
+		p.print(outdent, n.Label, token.Colon, indent, newline, n.Stmt)
+
 	case *ast.ReturnStmt:
 		p.print(token.Return)
 		if n.Result != nil {
@@ -463,10 +568,26 @@ func (p *printer) printRawNode(n ast.Node) {
 		}
 		p.print(n.Body)
 
+	case *ast.WhileStmt:
+		p.print(token.While, blank, n.Cond, blank, n.Body)
+
+	case *ast.SwitchStmt:
+		p.print(token.Switch, blank)
+		if n.Init != nil {
+			p.print(n.Init, token.Semi, blank)
+		}
+		if n.Tag != nil {
+			p.print(n.Tag, blank)
+		}
+		p.printSwitchBody(n.Body)
+
 	case *ast.ImportDecl:
 		if n.Group == nil {
 			p.print(token.Import, blank)
 		}
+		if n.LocalName != nil {
+			p.print(n.LocalName, blank)
+		}
 		p.print(n.Path)
 
 	case *ast.TypeDecl:
@@ -480,11 +601,21 @@ func (p *printer) printRawNode(n ast.Node) {
 		}
 		p.print(n.Type)
 
+	case *ast.ConstDecl:
+		if n.Group == nil {
+			p.print(token.Const, blank)
+		}
+		p.printNameList([]*ast.Name{n.NameList})
+		if n.Type != nil {
+			p.print(blank, n.Type)
+		}
+		p.print(blank, token.Assign, blank, n.Values)
+
 	case *ast.VarDecl:
 		if n.Group == nil {
 			p.print(token.Var, blank)
 		}
-		p.printNameList([]*ast.Name{n.NameList})
+		p.printNameList(n.NameList)
 		if n.Type != nil {
 			p.print(blank, n.Type)
 		}
@@ -495,21 +626,30 @@ func (p *printer) printRawNode(n ast.Node) {
 	case *ast.FuncDecl:
 		p.print(token.Func, blank)
 
-		// receiver not implemented
-		//if r := n.Recv; r != nil {
-		//	p.print(token.Lparen)
-		//	if r.Name != nil {
-		//		p.print(r.Name, blank)
-		//	}
-		//	p.printNode(r.Type)
-		//	p.print(token.Rparen, blank)
-		//}
+		if r := n.Recv; r != nil {
+			p.print(token.Lparen)
+			if r.Name != nil {
+				p.print(r.Name, blank)
+			}
+			p.printNode(r.Type)
+			p.print(token.Rparen, blank)
+		}
 		p.print(n.Name)
 		p.printSignature(n)
 		if n.Body != nil {
 			p.print(blank, n.Body)
 		}
 
+	case *ast.OperDecl:
+		p.print(token.Oper, blank)
+		p.printParameterList([]*ast.Field{n.TypeL}, 0)
+		p.print(blank, token.Name, n.Oper.OverloadName(), blank)
+		p.printParameterList([]*ast.Field{n.TypeR}, 0)
+		p.print(blank, n.Return)
+		if n.Body != nil {
+			p.print(blank, n.Body)
+		}
+
 	case *printGroup:
 		p.print(n.Tok, blank, token.Lparen)
 		if len(n.Decls) > 0 {
@@ -524,11 +664,18 @@ func (p *printer) printRawNode(n ast.Node) {
 
 	// files
 	case *ast.File:
-		p.print(token.Space, blank, n.SpaceName)
+		if n.SpaceName != nil {
+			p.print(token.Space, blank, n.SpaceName)
+			if len(n.DeclList) > 0 {
+				p.print(token.Semi, newline, newline)
+			}
+		}
 		if len(n.DeclList) > 0 {
-			p.print(token.Semi, newline, newline)
 			p.printDeclList(n.DeclList)
 		}
+		if p.linebreaks {
+			p.print(newline) // end the last line of the file
+		}
 
 	default:
 		panic(fmt.Sprintf("syntax.Iterate: unexpected node type %T", n))
@@ -563,16 +710,32 @@ func (p *printer) printExprLines(list []ast.Expr) {
 	}
 }
 
+// printLitElems prints the elements of a literal closed by the brace
+// at rbrace. As in the source, they go on lines of their own, each
+// followed by a comma, if the brace is on a later line than the last
+// element; otherwise, or in LineForm, they are printed inline.
+func (p *printer) printLitElems(list []ast.Expr, rbrace position.Pos) {
+	if p.linebreaks && len(list) > 0 && rbrace.Line() > list[len(list)-1].GetPos().Line() {
+		p.printExprLines(list)
+		return
+	}
+	p.printExprList(list)
+}
+
 func groupFor(d ast.Decl) (token.Token, *ast.Group) {
 	switch d := d.(type) {
 	case *ast.ImportDecl:
 		return token.Import, d.Group
 	case *ast.TypeDecl:
 		return token.Type, d.Group
+	case *ast.ConstDecl:
+		return token.Const, d.Group
 	case *ast.VarDecl:
 		return token.Var, d.Group
 	case *ast.FuncDecl:
 		return token.Func, nil
+	case *ast.OperDecl:
+		return token.Oper, nil
 	default:
 		panic("unreachable")
 	}
@@ -591,6 +754,9 @@ func (p *printer) printDecl(list []ast.Decl) {
 		if len(list) != 1 {
 			panic("unreachable")
 		}
+		if p.lines && p.linebreaks {
+			p.printLineDirective(list[0].GetPos())
+		}
 		p.printNode(list[0])
 		return
 	}
@@ -613,6 +779,20 @@ func (p *printer) printDecl(list []ast.Decl) {
 	p.printNode(&pg)
 }
 
+// printLineDirective writes a //line directive on a line of its own
+// which makes the following line the line of pos. Declarations start
+// in the first column, so the directive sets the column, too.
+func (p *printer) printLineDirective(pos position.Pos) {
+	if !pos.IsKnown() {
+		return
+	}
+	p.flush(0)
+	p.writeString(fmt.Sprintf("//line %s:%d:1", pos.Filename(), pos.RelLine()))
+	p.write(newlineByte)
+	p.nlcount = 1
+	p.lastTok = 0
+}
+
 func (p *printer) printDeclList(list []ast.Decl) {
 	i0 := 0
 	var tok token.Token
@@ -624,7 +804,8 @@ func (p *printer) printDeclList(list []ast.Decl) {
 				p.print(token.Semi, newline)
 				// print empty line between different declaration groups,
 				// different kinds of declarations, or between functions
-				if g != group || s != tok || s == token.Func {
+				// and operators
+				if g != group || s != tok || s == token.Func || s == token.Oper {
 					p.print(newline)
 				}
 				i0 = i
@@ -637,7 +818,16 @@ func (p *printer) printDeclList(list []ast.Decl) {
 
 func (p *printer) printSignature(fn *ast.FuncDecl) {
 	p.printParameterList(fn.Param, 0)
-	p.printNode(fn.Return)
+	if fn.Return != nil {
+		p.print(blank)
+		if list, ok := fn.Return.(*ast.ListExpr); ok {
+			p.print(token.Lparen)
+			p.printExprList(list.ElemList)
+			p.print(token.Rparen)
+		} else {
+			p.printNode(fn.Return)
+		}
+	}
 }
 
 // If tok != 0 print a type parameter list: tok == token.Type means
@@ -716,6 +906,33 @@ func (p *printer) printStmtList(list []ast.Stmt, braces bool) {
 	}
 }
 
+func (p *printer) printSwitchBody(list []*ast.CaseClause) {
+	p.print(token.Lbrace)
+	if len(list) > 0 {
+		p.print(newline)
+		for i, c := range list {
+			p.printCaseClause(c, i+1 == len(list))
+			p.print(newline)
+		}
+	}
+	p.print(token.Rbrace)
+}
+
+func (p *printer) printCaseClause(c *ast.CaseClause, braces bool) {
+	if c.CaseList != nil {
+		p.print(token.Case, blank)
+		p.printExprList(c.CaseList)
+	} else {
+		p.print(token.Default)
+	}
+	p.print(token.Colon)
+	if len(c.Body) > 0 {
+		p.print(newline, indent)
+		p.printStmtList(c.Body, braces)
+		p.print(outdent)
+	}
+}
+
 func (p *printer) printFields(fields []*ast.Field, tags []*ast.BasicLit, i, j int) {
 	if i+1 == j && fields[i].Name == nil {
 		// anonymous field
@@ -0,0 +1,637 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package parser
+
+import (
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/position"
+	"jindo/pkg/jindo/token"
+	"strings"
+	"testing"
+)
+
+// parseString parses src and fails the test on any syntax error.
+func parseString(t *testing.T, src string) *ast.File {
+	t.Helper()
+	f, err := Parse(position.NewFileBase("test.jindo"), strings.NewReader(src), func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCanonicalizeLoops(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\twhile c {}\n}\n")
+	body := f.DeclList[0].(*ast.FuncDecl).Body
+	pos := body.StmtList[0].GetPos()
+	ast.CanonicalizeLoops(f)
+
+	s, ok := body.StmtList[0].(*ast.ForStmt)
+	if !ok {
+		t.Fatalf("got %T, want *ast.ForStmt", body.StmtList[0])
+	}
+	if s.Init != nil || s.Post != nil || s.Cond == nil {
+		t.Errorf("got init=%v cond=%v post=%v, want condition-only for loop", s.Init, s.Cond, s.Post)
+	}
+	if s.GetPos() != pos {
+		t.Errorf("got position %s, want %s", s.GetPos(), pos)
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, LineForm); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "for c {}") {
+		t.Errorf("got %q, want it to contain %q", buf.String(), "for c {}")
+	}
+	verifyPrint(t, "test.jindo", f)
+
+	// loops nested in case clauses or labeled are rewritten too
+	f = parseString(t, "space main\nfunc f() {\n\tswitch x {\n\tcase 1:\n\t\twhile c {\n\t\t\twhile d {}\n\t\t}\n\t}\nL:\n\twhile e {}\n}\n")
+	ast.CanonicalizeLoops(f)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if _, ok := n.(*ast.WhileStmt); ok {
+			t.Errorf("got a while loop at %s after CanonicalizeLoops", n.GetPos())
+		}
+		return true
+	})
+}
+
+func TestPrintOperDecl(t *testing.T) {
+	const src = "space main\n\noper (a T) add (b T) T {\n\treturn a\n}\n\noper (a T) radd (b int) T {\n\treturn a\n}\n"
+	f := parseString(t, src)
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintDeclKinds(t *testing.T) {
+	const src = `space main
+
+import "fmt"
+
+type T int
+
+const c T = 1
+
+var v T
+
+func f() {}
+
+oper (a T) add (b T) T {
+	return a
+}
+`
+	f := parseString(t, src)
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintQualifiedReturnType(t *testing.T) {
+	for _, src := range []string{
+		"space main\n\nimport \"io\"\n\nfunc f() io.Writer {}\n",
+		"space main\n\nimport \"io\"\n\nfunc f() []io.Writer {}\n",
+	} {
+		f := parseString(t, src)
+		fn := f.DeclList[len(f.DeclList)-1].(*ast.FuncDecl)
+		if got := String(fn.Return); got != src[strings.LastIndex(src, ") ")+2:len(src)-4] {
+			t.Errorf("got return type %s", got)
+		}
+
+		var buf strings.Builder
+		if _, err := Fprint(&buf, f, 0); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != src {
+			t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+		}
+		verifyPrint(t, "test.jindo", f)
+	}
+}
+
+func TestPrintVarNameList(t *testing.T) {
+	for _, src := range []string{
+		"space main\n\nvar a, b, c int\n",
+		"space main\n\nvar a, b = 1, 2\n",
+		"space main\n\nvar a, b int = 1, 2\n",
+		"space main\n\nvar a = 1\n",
+		"space main\n\nfunc f() {\n\tvar x, y = y, x\n}\n",
+	} {
+		f := parseString(t, src)
+		var buf strings.Builder
+		if _, err := Fprint(&buf, f, 0); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != src {
+			t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+		}
+		verifyPrint(t, "test.jindo", f)
+	}
+}
+
+func TestPrintImportLocalName(t *testing.T) {
+	const src = "space main\n\nimport (\n\t\"geom/vec\"\n\tv \"geom/vec\"\n)\n"
+	f := parseString(t, src)
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+}
+
+func TestPrintResults(t *testing.T) {
+	for _, test := range []struct {
+		src string
+		n   int // number of result types
+	}{
+		{"space main\n\nfunc f() {}\n", 0},
+		{"space main\n\nfunc f() int {}\n", 1},
+		{"space main\n\nfunc f() (int, string) {}\n", 2},
+		{"space main\n\nfunc f(a int) (io.Writer, []T) {}\n", 2},
+	} {
+		f := parseString(t, test.src)
+		var n int
+		switch r := f.DeclList[0].(*ast.FuncDecl).Return.(type) {
+		case nil:
+		case *ast.ListExpr:
+			n = len(r.ElemList)
+		default:
+			n = 1
+		}
+		if n != test.n {
+			t.Errorf("%q: got %d result types, want %d", test.src, n, test.n)
+		}
+
+		var buf strings.Builder
+		if _, err := Fprint(&buf, f, 0); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.src {
+			t.Errorf("got\n%s\nwant\n%s", buf.String(), test.src)
+		}
+		verifyPrint(t, "test.jindo", f)
+	}
+}
+
+func TestPrintStructType(t *testing.T) {
+	const src = "space main\n\ntype Point struct {\n\tx, y int\n\tio.Writer\n\tT\n\tname string\n}\ntype Empty struct{}\n"
+	f := parseString(t, src)
+	st := f.DeclList[0].(*ast.TypeDecl).Type.(*ast.StructType)
+	var names []string
+	for _, field := range st.FieldList {
+		if field.Name == nil {
+			names = append(names, "embedded "+String(field.Type))
+		} else {
+			names = append(names, field.Name.Value)
+		}
+	}
+	if got, want := strings.Join(names, ", "), "x, y, embedded io.Writer, embedded T, name"; got != want {
+		t.Errorf("got fields %s, want %s", got, want)
+	}
+	if st.FieldList[0].Type != st.FieldList[1].Type {
+		t.Error("x and y do not share their type")
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintVariadic(t *testing.T) {
+	const src = "space main\n\nfunc f(a int, rest ...string) {}\n"
+	f := parseString(t, src)
+	fn := f.DeclList[0].(*ast.FuncDecl)
+	if dots, ok := fn.Param[1].Type.(*ast.DotsType); !ok || String(dots.Elem) != "string" {
+		t.Errorf("got parameter type %s, want ...string", String(fn.Param[1].Type))
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+
+	var errs []error
+	Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc f(a ...int, b int) {}\n"), func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "2:10: syntax error: can only use ... with final parameter in list") {
+		t.Errorf("got errors %v, want a non-final ... at 2:10", errs)
+	}
+}
+
+func TestPrintPointers(t *testing.T) {
+	const src = "space main\n\nvar p *int\n\nfunc f(q **Point) *int {\n\tp = &x\n\ty = *p * 2\n\t*p = *q.x\n\tr = &Point{x: 1}\n}\n"
+	f := parseString(t, src)
+	if v := f.DeclList[0].(*ast.VarDecl); String(v.Type) != "*int" {
+		t.Errorf("got var type %s, want *int", String(v.Type))
+	} else if _, ok := v.Type.(*ast.PointerType); !ok {
+		t.Errorf("got %T, want *ast.PointerType", v.Type)
+	}
+
+	body := f.DeclList[1].(*ast.FuncDecl).Body.StmtList
+	if x, ok := body[0].(*ast.AssignStmt).Rhs[0].(*ast.Operation); !ok || x.Op != token.And || x.Y != nil {
+		t.Errorf("got %s, want address-of", String(body[0].(*ast.AssignStmt).Rhs[0]))
+	}
+	if x, ok := body[1].(*ast.AssignStmt).Rhs[0].(*ast.Operation); !ok || x.Op != token.Mul || x.Y == nil {
+		t.Errorf("got %s, want a multiplication", String(body[1].(*ast.AssignStmt).Rhs[0]))
+	} else if d, ok := x.X.(*ast.Operation); !ok || d.Op != token.Mul || d.Y != nil {
+		t.Errorf("got %s, want a dereference", String(x.X))
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintMaps(t *testing.T) {
+	const src = "space main\n\nvar m map[string][]int\n\nfunc f(n map[string]map[int]bool) {\n\tm = map[string][]int{\"a\": v}\n\te = map[string]int{}\n}\n"
+	f := parseString(t, src)
+	if v, ok := f.DeclList[0].(*ast.VarDecl).Type.(*ast.MapType); !ok {
+		t.Errorf("got %s, want a map type", String(f.DeclList[0].(*ast.VarDecl).Type))
+	} else if _, ok := v.Value.(*ast.SliceType); !ok || String(v.Key) != "string" {
+		t.Errorf("got key %s and value %s, want string and []int", String(v.Key), String(v.Value))
+	}
+
+	body := f.DeclList[1].(*ast.FuncDecl).Body.StmtList
+	for i, want := range []int{1, 0} {
+		lit, ok := body[i].(*ast.AssignStmt).Rhs[0].(*ast.CompositeLit)
+		if !ok {
+			t.Errorf("stmt %d: got %s, want a composite literal", i, String(body[i]))
+			continue
+		}
+		if _, ok := lit.Type.(*ast.MapType); !ok || len(lit.ElemList) != want {
+			t.Errorf("stmt %d: got %s with %d elements, want a map literal with %d", i, String(lit), len(lit.ElemList), want)
+		}
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintMethods(t *testing.T) {
+	const src = "space main\n\nfunc (p *Point) Scale(k int) Point {\n\treturn p\n}\n\nfunc Scale(p Point, k int) Point {\n\treturn p\n}\n"
+	f := parseString(t, src)
+	m := f.DeclList[0].(*ast.FuncDecl)
+	if m.Recv == nil || m.Recv.Name.Value != "p" || String(m.Recv.Type) != "*Point" || m.Name.Value != "Scale" {
+		t.Errorf("got receiver %v of %s, want (p *Point) of Scale", m.Recv, m.Name.Value)
+	}
+	if fn := f.DeclList[1].(*ast.FuncDecl); fn.Recv != nil || len(fn.Param) != 2 {
+		t.Errorf("got receiver %v and %d parameters, want a function of 2", fn.Recv, len(fn.Param))
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+
+	for _, test := range []struct {
+		recv, want string
+	}{
+		{"()", "2:6: method has no receiver"},
+		{"(a T, b T)", "2:6: method has multiple receivers"},
+	} {
+		var errs []error
+		Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nfunc "+test.recv+" m() {}\n"), func(err error) { errs = append(errs, err) })
+		if len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), test.want) {
+			t.Errorf("%s: got errors %v, want %s", test.recv, errs, test.want)
+		}
+	}
+}
+
+func TestPrintLineDirectives(t *testing.T) {
+	parse := func(filename, src string) *ast.File {
+		f, err := Parse(position.NewFileBase(filename), strings.NewReader(src), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	a := parse("a.jindo", "space main\n\n\nfunc f() {\n\tx = 1\n}\n")
+	b := parse("b.jindo", "space main\n\ntype T int\n\n\n\nvar v T\n")
+
+	// assemble the declarations of both files into one
+	f := &ast.File{SpaceName: a.SpaceName, DeclList: append(a.DeclList, b.DeclList...)}
+	var buf strings.Builder
+	if _, err := FprintLines(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	const want = "space main\n\n//line a.jindo:4:1\nfunc f() {\n\tx = 1\n}\n\n//line b.jindo:3:1\ntype T int\n\n//line b.jindo:7:1\nvar v T\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	g := parse("gen.jindo", buf.String())
+	for i, want := range []string{"a.jindo:4:6", "b.jindo:3:6", "b.jindo:7:5"} {
+		if got := g.DeclList[i].GetPos().String(); got != want {
+			t.Errorf("declaration %d: got position %s, want %s", i, got, want)
+		}
+	}
+	body := g.DeclList[0].(*ast.FuncDecl).Body
+	if got := body.StmtList[0].GetPos().String(); got != "a.jindo:5:4" {
+		t.Errorf("got statement position %s, want a.jindo:5:4", got)
+	}
+}
+
+func TestPrintSliceExpr(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\ta = s[i]\n\ta = s[:]\n\ta = s[lo:]\n\ta = s[:hi]\n\ta = s[lo:hi]\n\ta = s[lo + 1:hi - 1]\n\ta = s[:hi:max]\n\ta = s[lo:hi:max]\n}\n"
+	f := parseString(t, src)
+	for i, s := range f.DeclList[0].(*ast.FuncDecl).Body.StmtList {
+		x := s.(*ast.AssignStmt).Rhs[0]
+		if _, ok := x.(*ast.IndexExpr); ok != (i == 0) {
+			t.Errorf("stmt %d: got %T for %s", i, x, String(x))
+		}
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintExponents(t *testing.T) {
+	const src = "space main\n\nconst a = 1e+10\nconst b = 1.5e-3\nconst c = 0x1p-2\nconst d = -2E-5\n"
+	f := parseString(t, src)
+	for i, want := range []string{"1e+10", "1.5e-3", "0x1p-2", "2E-5"} {
+		x := f.DeclList[i].(*ast.ConstDecl).Values
+		if op, ok := x.(*ast.Operation); ok {
+			x = op.X
+		}
+		if lit, ok := x.(*ast.BasicLit); !ok || lit.Kind != token.FloatLit || lit.Value != want {
+			t.Errorf("decl %d: got %s, want float literal %s", i, String(x), want)
+		}
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintLiteralLines(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\tmonths = []string{\n\t\t\"January\",\n\t\t\"February\",\n\t\t\"March\",\n\t}\n\tm = map[string]int{\n\t\t\"one\": 1,\n\t\t\"two\": 2,\n\t}\n\tshort = []int{1, 2, 3}\n\tp = Point{x, y}\n}\n"
+	f := parseString(t, src)
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+
+	// elements wrapped without a line break before the closing brace
+	// are joined onto one line, without a trailing comma
+	f = parseString(t, "space main\nfunc f() {\n\tsquares = []int{1, 4, 9,\n\t\t16, 25}\n}\n")
+	buf.Reset()
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := "space main\n\nfunc f() {\n\tsquares = []int{1, 4, 9, 16, 25}\n}\n"; buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintAddressOf(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\tp = &x\n\tq = &point.field\n\tr = &(T{1})\n}\n"
+	f := parseString(t, src)
+
+	body := f.DeclList[0].(*ast.FuncDecl).Body.StmtList
+	for i, want := range []string{"x", "point.field", "T{1}"} {
+		x, ok := body[i].(*ast.AssignStmt).Rhs[0].(*ast.Operation)
+		if !ok || x.Op != token.And || x.Y != nil {
+			t.Errorf("stmt %d: got %#v, want a unary & operation", i, body[i].(*ast.AssignStmt).Rhs[0])
+			continue
+		}
+		if got := String(x.X); got != want {
+			t.Errorf("stmt %d: got operand %s, want %s", i, got, want)
+		}
+	}
+
+	// the parentheses around a composite literal are dropped
+	want := strings.Replace(src, "&(T{1})", "&T{1}", 1)
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintIncDec(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\ti++\n\tp.n--\n\tfor i := 0; i < 10; i++ {\n\t\tg(i)\n\t}\n}\n"
+	f := parseString(t, src)
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintInferredLength(t *testing.T) {
+	const src = "space main\n\nfunc f() {\n\tprimes = [...]int{2, 3, 5}\n\tsquares = []int{1, 4, 9}\n}\n"
+	f := parseString(t, src)
+
+	body := f.DeclList[0].(*ast.FuncDecl).Body.StmtList
+	for i, want := range []bool{true, false} {
+		lit := body[i].(*ast.AssignStmt).Rhs[0].(*ast.SliceLit)
+		if lit.Dots != want {
+			t.Errorf("%s: got Dots = %v, want %v", String(lit), lit.Dots, want)
+		}
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintOperReturnTypes(t *testing.T) {
+	const src = "space main\n\noper (a T) add (b T) []int {\n\treturn nil\n}\n\noper (a T) mul (b T) *T {\n\treturn &a\n}\n\noper (a T) sub (b T) big.Int {\n\treturn a\n}\n\noper (a T) div (b T) map[string]T {\n\treturn nil\n}\n"
+	f := parseString(t, src)
+	for i, want := range []string{"[]int", "*T", "big.Int", "map[string]T"} {
+		if got := String(f.DeclList[i].(*ast.OperDecl).Return); got != want {
+			t.Errorf("decl %d: got return type %s, want %s", i, got, want)
+		}
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintNoResult(t *testing.T) {
+	f := parseString(t, "space main\nfunc f(a int) { }\nfunc g() {\n}\n")
+	for _, d := range f.DeclList {
+		if d := d.(*ast.FuncDecl); d.Return != nil {
+			t.Errorf("%s: got return type %s, want none", d.Name.Value, String(d.Return))
+		}
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := "space main\n\nfunc f(a int) {}\n\nfunc g() {}\n"; buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintGroups(t *testing.T) {
+	const src = "space main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n)\n\ntype (\n\tT int\n\tS []T\n)\n\nvar (\n\ta int\n\tb, c = 1, 2\n)\n\nvar d T\n"
+	f := parseString(t, src)
+	var groups []*ast.Group
+	for _, d := range f.DeclList {
+		var g *ast.Group
+		switch d := d.(type) {
+		case *ast.ImportDecl:
+			g = d.Group
+		case *ast.TypeDecl:
+			g = d.Group
+		case *ast.VarDecl:
+			g = d.Group
+		}
+		groups = append(groups, g)
+	}
+	if len(groups) != 7 {
+		t.Fatalf("got %d declarations, want 7", len(groups))
+	}
+	for i := 0; i < 6; i += 2 {
+		if groups[i] == nil || groups[i] != groups[i+1] {
+			t.Errorf("decls %d and %d: got groups %p and %p, want the same group", i, i+1, groups[i], groups[i+1])
+		}
+	}
+	if groups[6] != nil {
+		t.Errorf("got a group for the ungrouped var")
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+
+	// empty groups declare nothing
+	f = parseString(t, "space main\nimport ()\nvar ()\ntype ()\nvar x int\n")
+	if len(f.DeclList) != 1 {
+		t.Errorf("got %d declarations, want 1", len(f.DeclList))
+	}
+}
+
+func TestPrintLocalTypes(t *testing.T) {
+	const src = "space main\n\nfunc f() int {\n\ttype T int\n\ttype (\n\t\tP struct {\n\t\t\tx, y T\n\t\t}\n\t\tS []P\n\t)\n\tvar s S\n\treturn len(s)\n}\n"
+	f := parseString(t, src)
+	s, ok := f.DeclList[0].(*ast.FuncDecl).Body.StmtList[0].(*ast.DeclStmt)
+	if !ok || len(s.DeclList) != 1 {
+		t.Fatalf("got %s, want a declaration statement", String(f.DeclList[0].(*ast.FuncDecl).Body.StmtList[0]))
+	}
+	if d, ok := s.DeclList[0].(*ast.TypeDecl); !ok || d.Name.Value != "T" {
+		t.Errorf("got %s, want type T", String(s.DeclList[0]))
+	}
+
+	var buf strings.Builder
+	if _, err := Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), src)
+	}
+	verifyPrint(t, "test.jindo", f)
+}
+
+func TestPrintFinalNewline(t *testing.T) {
+	for _, src := range []string{
+		"space main",
+		"space main\nfunc f() {}",
+		"space main\nfunc f() {}\n\n\n",
+		"space main\nvar (\n\tx int\n)\n// trailing comment\n",
+	} {
+		f := parseString(t, src)
+		var buf strings.Builder
+		if _, err := Fprint(&buf, f, 0); err != nil {
+			t.Fatal(err)
+		}
+		if out := buf.String(); !strings.HasSuffix(out, "\n") || strings.HasSuffix(out, "\n\n") {
+			t.Errorf("%q: got %q, want output ending in exactly one newline", src, out)
+		}
+
+		// the single-line forms are unchanged
+		for _, form := range []Form{LineForm, ShortForm} {
+			buf.Reset()
+			if _, err := Fprint(&buf, f, form); err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasSuffix(buf.String(), "\n") {
+				t.Errorf("%q: got %q in form %d, want no final newline", src, buf.String(), form)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package position
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A TextEdit replaces the bytes src[Start:End] of a source by NewText.
+// An edit with Start == End is an insertion.
+type TextEdit struct {
+	Start, End int // byte offsets
+	NewText    string
+}
+
+// ApplyEdits returns a copy of src with all edits applied. The edits are
+// applied in order of their start offsets; insertions at the same offset
+// keep their relative order. Edits must not overlap or exceed src.
+func ApplyEdits(src []byte, edits []TextEdit) ([]byte, error) {
+	sorted := make([]TextEdit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	out := make([]byte, 0, len(src))
+	last := 0 // end of the previous edit
+	for _, e := range sorted {
+		if e.Start < 0 || e.Start > e.End || e.End > len(src) {
+			return nil, fmt.Errorf("invalid edit range [%d:%d] for source of length %d", e.Start, e.End, len(src))
+		}
+		if e.Start < last {
+			return nil, fmt.Errorf("edit [%d:%d] overlaps previous edit ending at %d", e.Start, e.End, last)
+		}
+		out = append(out, src[last:e.Start]...)
+		out = append(out, e.NewText...)
+		last = e.End
+	}
+	return append(out, src[last:]...), nil
+}
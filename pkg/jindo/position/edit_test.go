@@ -0,0 +1,34 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package position
+
+import "testing"
+
+func TestApplyEdits(t *testing.T) {
+	src := []byte("var x int")
+	got, err := ApplyEdits(src, []TextEdit{
+		{Start: 4, End: 5, NewText: "y"},        // replacement
+		{Start: 0, End: 0, NewText: "// doc\n"}, // insertion
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "// doc\nvar y int"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if string(src) != "var x int" {
+		t.Errorf("source was modified: %q", src)
+	}
+
+	_, err = ApplyEdits(src, []TextEdit{
+		{Start: 0, End: 5, NewText: "const y"},
+		{Start: 4, End: 9, NewText: "z"},
+	})
+	if err == nil {
+		t.Error("expected an error for overlapping edits")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package position
+
+import (
+	"sort"
+	"sync"
+)
+
+// A FileSet registers the position bases of the files of a space, in the
+// order they are added, so that positions in different files can be
+// ordered consistently. A FileSet is safe for concurrent use, so files
+// may be added while others are parsed; their order is then the order
+// in which the calls of AddFile happen.
+type FileSet struct {
+	mu    sync.RWMutex // protects bases and index
+	bases []*PosBase
+	index map[*PosBase]int
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{index: make(map[*PosBase]int)}
+}
+
+// AddFile returns a new file base for filename and adds it to s.
+func (s *FileSet) AddFile(filename string) *PosBase {
+	base := NewFileBase(filename)
+	s.mu.Lock()
+	s.index[base] = len(s.bases)
+	s.bases = append(s.bases, base)
+	s.mu.Unlock()
+	return base
+}
+
+// Files returns the file bases of s in the order they were added.
+func (s *FileSet) Files() []*PosBase {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*PosBase(nil), s.bases...)
+}
+
+// Cmp compares p and q like Pos.Cmp, except that positions in different
+// files are ordered like the files were added to s. Positions in files
+// which are not in s come last, ordered by file name.
+func (s *FileSet) Cmp(p, q Pos) int {
+	s.mu.RLock()
+	pi, qi, n := s.fileIndex(p), s.fileIndex(q), len(s.bases)
+	s.mu.RUnlock()
+	switch {
+	case pi < qi:
+		return -1
+	case pi > qi:
+		return +1
+	case pi < n:
+		// same file; ignore the file names of line bases
+		p.base, q.base = nil, nil
+	}
+	return p.Cmp(q)
+}
+
+// Sort sorts list in the order defined by Cmp.
+func (s *FileSet) Sort(list []Pos) {
+	sort.SliceStable(list, func(i, j int) bool { return s.Cmp(list[i], list[j]) < 0 })
+}
+
+// fileIndex returns the index of the file containing p,
+// or len(s.bases) if it is not in s. s.mu must be held.
+func (s *FileSet) fileIndex(p Pos) int {
+	b := p.base
+	for b != nil && b.pos.base != b {
+		b = b.pos.base // line base; continue with the base containing it
+	}
+	if i, ok := s.index[b]; ok {
+		return i
+	}
+	return len(s.bases)
+}
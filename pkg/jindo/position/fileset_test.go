@@ -0,0 +1,32 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package position
+
+import "testing"
+
+func TestFileSetCmp(t *testing.T) {
+	s := NewFileSet()
+	b := s.AddFile("b.jindo")
+	a := s.AddFile("a.jindo")
+	other := NewFileBase("0.jindo")
+	line := NewLineBase(MakePos(a, 3, 1), "gen.jindo", 10, 1)
+
+	list := []Pos{
+		MakePos(other, 1, 1),
+		MakePos(a, 5, 1),
+		MakePos(line, 4, 1),
+		MakePos(b, 2, 1),
+		MakePos(b, 1, 4),
+	}
+	s.Sort(list)
+	want := []string{"b.jindo:1:4", "b.jindo:2:1", "gen.jindo:11:1", "a.jindo:5:1", "0.jindo:1:1"}
+	for i, p := range list {
+		if p.String() != want[i] {
+			t.Errorf("position %d: got %s, want %s", i, p, want[i])
+		}
+	}
+}
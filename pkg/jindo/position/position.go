@@ -32,8 +32,13 @@ func NewFileBase(filename string) *PosBase {
 	return base
 }
 
+// String returns p as "filename:line:col", relative to the most recent
+// line directive, if any. The column is omitted if it is unknown.
 func (p Pos) String() string {
-	return fmt.Sprintf("%s:%d:%d", p.base.Filename(), p.line, p.col)
+	if col := p.RelCol(); col > 0 {
+		return fmt.Sprintf("%s:%d:%d", p.base.Filename(), p.RelLine(), col)
+	}
+	return fmt.Sprintf("%s:%d", p.base.Filename(), p.RelLine())
 }
 
 type PosBase struct {
@@ -46,12 +51,74 @@ func (b PosBase) Filename() string {
 	return b.filename
 }
 
+// Pos returns the position at which b takes effect: that of its line
+// directive, or the start of the file for a file base.
+func (b PosBase) Pos() Pos {
+	return b.pos
+}
+
 // func (pos pos) IsKnown() bool  { return pos.line > 0 }
 
-func (p Pos) Pos() Pos      { return p }
-func (p Pos) Line() uint    { return p.line }
-func (p Pos) Col() uint     { return p.col }
-func (p Pos) IsKnown() bool { return p.line > 0 }
+func (p Pos) Pos() Pos       { return p }
+func (p Pos) Base() *PosBase { return p.base }
+func (p Pos) Line() uint     { return p.line }
+func (p Pos) Col() uint      { return p.col }
+func (p Pos) IsKnown() bool  { return p.line > 0 }
+
+// RelLine returns the line number of p as set by the most recent
+// line directive, or its line number in the source if there is none.
+func (p Pos) RelLine() uint {
+	b := p.base
+	if b == nil || b.line == 0 {
+		return 0
+	}
+	return uint(b.line) + p.line - b.pos.line
+}
+
+// RelCol returns the column of p relative to the most recent line
+// directive, or 0 if the directive did not specify a column.
+func (p Pos) RelCol() uint {
+	b := p.base
+	if b == nil || b.col == 0 {
+		return 0
+	}
+	if p.line == b.pos.line {
+		// p is on the line of the directive
+		return uint(b.col) + p.col - b.pos.col
+	}
+	return p.col
+}
+
+// Filename returns the name of the file holding p, or "" if p has no base.
+func (p Pos) Filename() string {
+	if p.base == nil {
+		return ""
+	}
+	return p.base.Filename()
+}
+
+// Cmp compares the positions p and q and returns -1 if p precedes q,
+// 0 if they are the same, and +1 if p follows q. Positions in
+// different files are ordered by file name.
+func (p Pos) Cmp(q Pos) int {
+	switch pname, qname := p.Filename(), q.Filename(); {
+	case pname < qname:
+		return -1
+	case pname > qname:
+		return +1
+	}
+	switch {
+	case p.line < q.line:
+		return -1
+	case p.line > q.line:
+		return +1
+	case p.col < q.col:
+		return -1
+	case p.col > q.col:
+		return +1
+	}
+	return 0
+}
 
 func sat32(x uint) uint32 {
 	if x > PosMax {
@@ -0,0 +1,94 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package refactor
+
+import (
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/parser"
+	"jindo/pkg/jindo/position"
+	"strings"
+	"testing"
+)
+
+func parseString(t *testing.T, src string) *ast.File {
+	t.Helper()
+	f, err := parser.Parse(position.NewFileBase("test.jindo"), strings.NewReader(src), func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func fprint(t *testing.T, f *ast.File) string {
+	t.Helper()
+	var buf strings.Builder
+	if _, err := parser.Fprint(&buf, f, 0); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+const renameSrc = `space main
+
+func f(x int) int {
+	return x
+}
+
+func h() {}
+
+func main() {
+	var y = f(1)
+	f(f(y))
+}
+`
+
+func TestRename(t *testing.T) {
+	f := parseString(t, renameSrc)
+	fn := f.DeclList[0].(*ast.FuncDecl)
+
+	// rename via a call site rather than the declaration
+	call := ast.FindCalls(f, "f")[0]
+	if err := Rename(f, call.Func.(*ast.Name), "g"); err != nil {
+		t.Fatal(err)
+	}
+	if fn.Name.Value != "g" {
+		t.Errorf("got declaration %s, want g", fn.Name.Value)
+	}
+	if n := len(ast.FindCalls(f, "g")); n != 3 {
+		t.Errorf("got %d calls of g, want 3", n)
+	}
+	if n := len(ast.FindCalls(f, "f")); n != 0 {
+		t.Errorf("got %d calls of f, want 0", n)
+	}
+	if got, want := fprint(t, f), strings.ReplaceAll(renameSrc, "f(", "g("); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenameConflict(t *testing.T) {
+	f := parseString(t, renameSrc)
+	fn := f.DeclList[0].(*ast.FuncDecl)
+	y := f.DeclList[2].(*ast.FuncDecl).Body.StmtList[0].(*ast.DeclStmt).DeclList[0].(*ast.VarDecl).NameList[0]
+
+	for _, test := range []struct {
+		target  *ast.Name
+		newName string
+		want    string
+	}{
+		{fn.Name, "h", "cannot rename f to h: conflicts with an existing declaration"},
+		{y, "f", "cannot rename y to f: conflicts with an existing declaration"}, // f(f(y)) would refer to y
+		{fn.Name, "for", `invalid name "for"`},
+	} {
+		err := Rename(f, test.target, test.newName)
+		if err == nil || !strings.HasSuffix(err.Error(), test.want) {
+			t.Errorf("got %v, want %s", err, test.want)
+		}
+	}
+	if got := fprint(t, f); got != renameSrc {
+		t.Errorf("file changed after failed renames:\n%s", got)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+// Package refactor implements source-level refactorings
+// over resolved jindo syntax trees.
+package refactor
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/sema"
+	"jindo/pkg/jindo/token"
+	"unicode"
+)
+
+// Rename renames, in place, the entity declared or denoted by target
+// and every reference to it in f. Rename fails and leaves f unchanged
+// if target does not refer to a declaration in f, if newName is not
+// a valid identifier, or if newName would collide with another
+// declaration: either by redeclaring a name in the same scope or by
+// changing what some identifier in f refers to.
+func Rename(f *ast.File, target *ast.Name, newName string) error {
+	if !isIdent(newName) {
+		return fmt.Errorf("%s: invalid name %q", target.Pos, newName)
+	}
+
+	files := []*ast.File{f}
+	info, errs := sema.Resolve(files)
+	obj := info.Defs[target]
+	if obj == nil {
+		obj = info.Uses[target]
+	}
+	if obj == nil || obj.Decl == nil || obj.Kind == sema.SpaceObj {
+		return fmt.Errorf("%s: cannot rename %s: not declared in this file", target.Pos, target.Value)
+	}
+
+	var names []*ast.Name
+	for _, m := range []map[*ast.Name]*sema.Object{info.Defs, info.Uses} {
+		for n, o := range m {
+			if o == obj {
+				names = append(names, n)
+			}
+		}
+	}
+
+	oldName := obj.Name
+	for _, n := range names {
+		n.Value = newName
+	}
+
+	// Resolve again: the rename is safe if it introduced no errors
+	// and every identifier still refers to the same declaration.
+	info2, errs2 := sema.Resolve(files)
+	if len(errs2) > len(errs) || !sameUses(info, info2) {
+		for _, n := range names {
+			n.Value = oldName
+		}
+		return fmt.Errorf("%s: cannot rename %s to %s: conflicts with an existing declaration", target.Pos, oldName, newName)
+	}
+	return nil
+}
+
+// sameUses reports whether each identifier used in a denotes the
+// same declaration in b.
+func sameUses(a, b *sema.Info) bool {
+	keyA, keyB := declKeys(a), declKeys(b)
+	for n, obj := range a.Uses {
+		if key(keyA, obj) != key(keyB, b.Uses[n]) {
+			return false
+		}
+	}
+	return true
+}
+
+// declKeys maps each object declared in info to its declaring identifier,
+// which identifies the object across separate runs of sema.Resolve.
+func declKeys(info *sema.Info) map[*sema.Object]*ast.Name {
+	keys := make(map[*sema.Object]*ast.Name, len(info.Defs))
+	for n, obj := range info.Defs {
+		keys[obj] = n
+	}
+	return keys
+}
+
+func key(keys map[*sema.Object]*ast.Name, obj *sema.Object) any {
+	switch {
+	case obj == nil:
+		return nil
+	case keys[obj] != nil:
+		return keys[obj]
+	case obj.Decl != nil:
+		return obj.Decl // imported space
+	}
+	return obj // predeclared
+}
+
+func isIdent(s string) bool {
+	if s == "" || s == "_" || token.KeywordOrName(s) != token.Name {
+		return false
+	}
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package scanner
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// tokenCases returns the labels of the cases of the switch on s.ch in
+// Scanner.next, as read from scanner.go, by the characters they match.
+// The default case has the label "default", and identifiers, scanned
+// ahead of the switch, the label "identifier".
+func tokenCases(t *testing.T) map[rune]string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "scanner.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := make(map[rune]string)
+	for _, d := range f.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "next" {
+			continue
+		}
+		for _, s := range fn.Body.List {
+			sw, ok := s.(*ast.SwitchStmt)
+			if !ok {
+				continue
+			}
+			for _, c := range sw.Body.List {
+				var chars []rune
+				for _, x := range c.(*ast.CaseClause).List {
+					chars = append(chars, caseChar(t, x))
+				}
+				label := "default"
+				if len(chars) > 0 {
+					label = caseLabel(chars[0])
+				}
+				for _, ch := range chars {
+					cases[ch] = label
+				}
+			}
+		}
+	}
+	if len(cases) == 0 {
+		t.Fatal("no token cases found in Scanner.next")
+	}
+	return cases
+}
+
+// caseChar returns the character matched by the case expression x,
+// either a rune literal or -1.
+func caseChar(t *testing.T, x ast.Expr) rune {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		if x.Kind == token.CHAR {
+			v, _, _, err := strconv.UnquoteChar(x.Value[1:len(x.Value)-1], '\'')
+			if err == nil {
+				return v
+			}
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := x.X.(*ast.BasicLit); ok && x.Op == token.SUB && lit.Value == "1" {
+			return -1
+		}
+	}
+	t.Fatalf("unexpected case expression %T", x)
+	return 0
+}
+
+func caseLabel(ch rune) string {
+	if ch == -1 {
+		return "EOF"
+	}
+	return strconv.QuoteRune(ch)
+}
+
+// coveredCases scans src and returns the labels of the token cases it
+// reaches.
+func coveredCases(t *testing.T, src string, cases map[rune]string) map[string]bool {
+	t.Helper()
+	covered := make(map[string]bool)
+	coverCase = func(ch rune) {
+		switch {
+		case isLetter(ch) || ch >= utf8.RuneSelf && ch != -1:
+			covered["identifier"] = true
+		case cases[ch] != "":
+			covered[cases[ch]] = true
+		default:
+			covered["default"] = true
+		}
+	}
+	defer func() { coverCase = nil }()
+	scanMsgs(src, 0)
+	return covered
+}
+
+func TestTokenCaseCoverage(t *testing.T) {
+	const src = "space main\n" +
+		"import \"io\"\n" +
+		"func f(a ...int) {\n" +
+		"\tx := []int{1, 2}[0:1]; y = 'c' + `raw` + \"\"\"multi\"\"\" + .5\n" +
+		"\tx -= y * z / w % v & u | t ^ s &^ r << 1 >> 2\n" +
+		"\tif a < b && c > d || e <= f && g >= h && i == j && k != !l {\n" +
+		"\t\tp.q = 0..9 // line comment\n" +
+		"\t} /* block comment */\n" +
+		"\t* x; x++; x--\n" +
+		"\t#\n" +
+		"}\n"
+
+	cases := tokenCases(t)
+	covered := coveredCases(t, src, cases)
+	if !covered["identifier"] {
+		t.Errorf("identifiers not reached")
+	}
+	var missing []string
+	seen := make(map[string]bool)
+	for _, label := range cases {
+		if !covered[label] && !seen[label] {
+			missing = append(missing, label)
+		}
+		seen[label] = true
+	}
+	if !covered["default"] {
+		missing = append(missing, "default")
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		t.Errorf("token cases not reached: %s", strings.Join(missing, ", "))
+	}
+
+	// a smaller input reaches only some of the cases
+	covered = coveredCases(t, "x := 1\n", cases)
+	for _, label := range []string{"identifier", "':'", "'0'", "'\\n'", "EOF"} {
+		if !covered[label] {
+			t.Errorf("%s not reached", label)
+		}
+	}
+	if covered["'+'"] || covered["default"] {
+		t.Errorf("got unexpected cases reached: %v", covered)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"jindo/pkg/jindo/token"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -18,8 +19,10 @@ import (
 // by calling the error handler. If no flag is set, comments
 // are ignored.
 const (
-	comments   uint = 1 << iota // call handler for all comments
-	directives                  // call handler for directives only
+	Comments      uint = 1 << iota // call handler for all comments
+	Directives                     // call handler for directives only
+	Trailing                       // call handler for lines ending in blanks
+	Interpolation                  // split "...${x}..." strings into segments and expressions (experimental)
 )
 
 type Scanner struct {
@@ -29,13 +32,46 @@ type Scanner struct {
 
 	// current token, valid after calling Next()
 	line, col uint
+	offs, end int  // source byte offsets of the token start and end
 	blank     bool // line is blank up to col
 	token     token.Token
 	lit       string         // valid if token is token.Name, token.Literal, or token.Semi ("semicolon", "newline", or "fileOrEof"); may be malformed if bad is true
 	bad       bool           // valid if token is token.Literal, true if a syntax error occurred, lit may be malformed
 	kind      token.LitKind  // valid if token is token.Literal
 	op        token.Operator // valid if token is token.Op, token.Star, token.AssignOp, or token.IncOp
-	prec      int            // valid if token is token.Op, token.Star, token.AssignOp, or token.IncOp
+	prec      int            // valid if token is token.Op, token.Star, token.AssignOp, token.IncOp, or token.DotDot
+
+	// start of the current run of blanks, valid if the Trailing mode is set
+	blankLine, blankCol uint
+
+	ahead  tokenState // token following the current one, valid if peeked is set
+	peeked bool
+
+	// brace nesting depth within each open ${...}, innermost last;
+	// valid if the Interpolation mode is set
+	interp []int
+}
+
+// tokenState holds the current token fields of a Scanner.
+type tokenState struct {
+	line, col uint
+	offs, end int
+	blank     bool
+	token     token.Token
+	lit       string
+	bad       bool
+	kind      token.LitKind
+	op        token.Operator
+	prec      int
+}
+
+func (s *Scanner) save() tokenState {
+	return tokenState{s.line, s.col, s.offs, s.end, s.blank, s.token, s.lit, s.bad, s.kind, s.op, s.prec}
+}
+
+func (s *Scanner) restore(t tokenState) {
+	s.line, s.col, s.offs, s.end, s.blank = t.line, t.col, t.offs, t.end, t.blank
+	s.token, s.lit, s.bad, s.kind, s.op, s.prec = t.token, t.lit, t.bad, t.kind, t.op, t.prec
 }
 
 func (s *Scanner) Token() token.Token  { return s.token }
@@ -47,10 +83,37 @@ func (s *Scanner) Prec() int           { return s.prec }
 func (s *Scanner) Line() uint          { return s.line }
 func (s *Scanner) Col() uint           { return s.col }
 
+// Offset returns the byte offset of the start of the current token.
+func (s *Scanner) Offset() int { return s.offs }
+
+// EndOffset returns the byte offset immediately following the current
+// token. For a ';' inserted at the end of the source, it is Offset.
+func (s *Scanner) EndOffset() int { return s.end }
+
+// Init is like InitMode with no mode flags set.
 func (s *Scanner) Init(src io.Reader, errh func(line, col uint, msg string)) {
+	s.InitMode(src, errh, 0)
+}
+
+// InitMode prepares s to scan src. The mode flags control which comments
+// are passed to errh, as described for Next. If mode includes Trailing,
+// each line ending in blanks (spaces or tabs before a newline) outside of
+// comments and literals is reported as well, with the position of the
+// first trailing blank and a message starting with "warning: ".
+//
+// If mode includes Interpolation, a "-delimited string holding ${...}
+// is scanned as a sequence of segments and the tokens of the embedded
+// expressions: "a${x}b${y}c" yields the StringHead "a${, the Name x,
+// the StringMid }b${, the Name y and the StringTail }c". The literal of
+// each segment is its source text, in which \$ stands for a literal $.
+// Otherwise, $ has no special meaning.
+func (s *Scanner) InitMode(src io.Reader, errh func(line, col uint, msg string), mode uint) {
 	s.source.init(src, errh)
-	//s.mode = mode
+	s.mode = mode
 	s.nlsemi = false
+	s.blankLine, s.blankCol = 0, 0
+	s.peeked = false
+	s.interp = s.interp[:0]
 }
 
 // errorf reports an error at the most recently read character position.
@@ -79,17 +142,48 @@ func (s *Scanner) setLit(kind token.LitKind, ok bool) {
 // and message. The error message is guaranteed to be non-empty and
 // never starts with a '/'. The error handler must exist.
 //
-// If the scanner mode includes the comments flag and a comment
+// If the scanner mode includes the Comments flag and a comment
 // (including comments containing directives) is encountered, the
 // error handler is also called with each comment position and text
 // (including opening /* or // and closing */, but without a newline
 // at the end of line comments). Comment text always starts with a /
 // which can be used to distinguish these handler calls from errors.
 //
-// If the scanner mode includes the directives (but not the comments)
+// If the scanner mode includes the Directives (but not the Comments)
 // flag, only comments containing a //line, /*line, or //go: directive
 // are reported, in the same way as regular comments.
 func (s *Scanner) Next() {
+	if s.peeked {
+		s.restore(s.ahead)
+		s.peeked = false
+		return
+	}
+	s.next()
+	s.end = s.offset()
+}
+
+// Peek returns the token following the current one, and its literal,
+// without advancing the scanner. Calls of the error handler for the
+// source text up to the end of the returned token happen during Peek.
+func (s *Scanner) Peek() (token.Token, string) {
+	if !s.peeked {
+		cur := s.save()
+		s.next()
+		s.end = s.offset()
+		s.ahead = s.save()
+		s.peeked = true
+		s.restore(cur)
+	}
+	return s.ahead.token, s.ahead.lit
+}
+
+// coverCase, if not nil, is called by next with the character that
+// selects how the token starting at it is scanned, that is the case
+// of the switch on s.ch below, or an identifier. Tests set it to find
+// the cases their inputs do not reach.
+var coverCase func(ch rune)
+
+func (s *Scanner) next() {
 	nlsemi := s.nlsemi
 	s.nlsemi = false
 
@@ -98,13 +192,23 @@ redo:
 	s.stop()
 	startLine, startCol := s.pos()
 	for s.ch == ' ' || s.ch == '\t' || s.ch == '\n' && !nlsemi || s.ch == '\r' {
+		if s.mode&Trailing != 0 {
+			s.trackBlank()
+		}
 		s.nextch()
 	}
+	if s.mode&Trailing != 0 {
+		s.trackBlank()
+	}
 
 	// token start
 	s.line, s.col = s.pos()
+	s.offs = s.offset()
 	s.blank = s.line > startLine || startCol == colbase
 	s.start()
+	if coverCase != nil {
+		coverCase(s.ch)
+	}
 	if isLetter(s.ch) || s.ch >= utf8.RuneSelf && s.atIdentChar(true) {
 		s.nextch()
 		s.ident()
@@ -129,7 +233,18 @@ redo:
 		s.number(false)
 
 	case '"':
-		s.stdString()
+		s.nextch()
+		if s.ch == '"' {
+			s.nextch()
+			if s.ch == '"' {
+				s.nextch()
+				s.multiString()
+				break
+			}
+			s.setLit(token.StringLit, true) // empty string
+			break
+		}
+		s.stdString(false)
 
 	case '`':
 		s.rawString()
@@ -147,6 +262,9 @@ redo:
 
 	case '{':
 		s.nextch()
+		if n := len(s.interp); n > 0 {
+			s.interp[n-1]++
+		}
 		s.token = token.Lbrace
 
 	case ',':
@@ -170,6 +288,15 @@ redo:
 
 	case '}':
 		s.nextch()
+		if n := len(s.interp); n > 0 {
+			if s.interp[n-1] == 0 {
+				// end of ${...}: the string resumes
+				s.interp = s.interp[:n-1]
+				s.stdString(true)
+				break
+			}
+			s.interp[n-1]--
+		}
 		s.nlsemi = true
 		s.token = token.Rbrace
 
@@ -195,8 +322,9 @@ redo:
 				s.token = token.DotDotDot
 				break
 			}
-			s.rewind() // now s.ch holds 1st '.'
-			s.nextch() // consume 1st '.' again
+			s.prec = token.PrecRange
+			s.token = token.DotDot
+			break
 		}
 		s.token = token.Dot
 
@@ -302,6 +430,12 @@ redo:
 			s.op, s.prec = token.Shl, token.PrecMul
 			goto assignop
 		}
+		if s.ch == '-' {
+			s.nextch()
+			s.op, s.prec = token.NoneOp, 0
+			s.token = token.Arrow
+			break
+		}
 		s.op, s.prec = token.Lss, token.PrecCmp
 		s.token = token.Op
 
@@ -359,6 +493,25 @@ assignop:
 	s.token = token.Op
 }
 
+// trackBlank records the start of a run of blanks at s.ch and
+// reports the run if s.ch is the newline ending it.
+func (s *Scanner) trackBlank() {
+	switch s.ch {
+	case ' ', '\t':
+		if s.blankCol == 0 {
+			s.blankLine, s.blankCol = s.pos()
+		}
+		return
+	case '\r':
+		return // part of a CRLF line ending
+	case '\n':
+		if s.blankCol != 0 {
+			s.errh(s.blankLine, s.blankCol, "warning: trailing whitespace")
+		}
+	}
+	s.blankCol = 0
+}
+
 func (s *Scanner) ident() {
 	// accelerate common case (7bit ASCII)
 	for isLetter(s.ch) || isDecimal(s.ch) {
@@ -461,7 +614,7 @@ func (s *Scanner) number(seenPoint bool) {
 			}
 		}
 		digsep |= s.digits(base, &invalid)
-		if s.ch == '.' {
+		if s.ch == '.' && s.peek() != '.' { // 1..2 is a range, not 1. followed by .2
 			if prefix == 'o' || prefix == 'b' {
 				s.errorf("invalid radix point in %s literal", baseName(base))
 				ok = false
@@ -632,17 +785,39 @@ func (s *Scanner) rune() {
 	s.setLit(token.RuneLit, ok)
 }
 
-func (s *Scanner) stdString() {
+// stdString scans a "-delimited string. The opening " has already been
+// consumed, or, if resumed is set, the } ending an interpolation within
+// the string. In Interpolation mode, the string or its remainder ends
+// at the next ${ as well.
+func (s *Scanner) stdString(resumed bool) {
 	ok := true
-	s.nextch()
 
 	for {
 		if s.ch == '"' {
 			s.nextch()
 			break
 		}
+		if s.ch == '$' && s.mode&Interpolation != 0 {
+			s.nextch()
+			if s.ch != '{' {
+				continue
+			}
+			s.nextch()
+			s.setLit(token.StringLit, ok)
+			s.nlsemi = false // an expression follows
+			s.token = token.StringHead
+			if resumed {
+				s.token = token.StringMid
+			}
+			s.interp = append(s.interp, 0)
+			return
+		}
 		if s.ch == '\\' {
 			s.nextch()
+			if s.ch == '$' && s.mode&Interpolation != 0 {
+				s.nextch() // \$ is a literal $
+				continue
+			}
 			if !s.escape('"') {
 				ok = false
 			}
@@ -662,6 +837,42 @@ func (s *Scanner) stdString() {
 	}
 
 	s.setLit(token.StringLit, ok)
+	if resumed {
+		s.token = token.StringTail
+	}
+}
+
+// multiString scans a """-delimited string, which may span multiple
+// lines. The opening """ has already been consumed. CRLF line endings
+// inside the string are normalized to LF in the literal.
+func (s *Scanner) multiString() {
+	ok := true
+	quotes := 0 // number of consecutive '"' seen
+	for quotes < 3 {
+		if s.ch == '"' {
+			quotes++
+			s.nextch()
+			continue
+		}
+		quotes = 0
+		if s.ch == '\\' {
+			s.nextch()
+			if !s.escape('"') {
+				ok = false
+			}
+			continue
+		}
+		if s.ch < 0 {
+			s.errorAtf(0, "string not terminated")
+			ok = false
+			break
+		}
+		s.nextch()
+	}
+
+	s.setLit(token.StringLit, ok)
+	s.lit = strings.ReplaceAll(s.lit, "\r\n", "\n")
+	s.lit = strings.ReplaceAll(s.lit, "\r", "\n") // lone carriage returns end lines, too
 }
 
 func (s *Scanner) rawString() {
@@ -701,14 +912,14 @@ func (s *Scanner) skipLine() {
 func (s *Scanner) lineComment() {
 	// opening has already been consumed
 
-	if s.mode&comments != 0 {
+	if s.mode&Comments != 0 {
 		s.skipLine()
 		s.comment(string(s.Segment()))
 		return
 	}
 
 	// are we saving directives? or is this definitely not a directive?
-	if s.mode&directives == 0 || (s.ch != 'g' && s.ch != 'l') {
+	if s.mode&Directives == 0 || (s.ch != 'g' && s.ch != 'l') {
 		s.stop()
 		s.skipLine()
 		return
@@ -751,14 +962,14 @@ func (s *Scanner) skipComment() bool {
 func (s *Scanner) fullComment() {
 	/* opening has already been consumed */
 
-	if s.mode&comments != 0 {
+	if s.mode&Comments != 0 {
 		if s.skipComment() {
 			s.comment(string(s.Segment()))
 		}
 		return
 	}
 
-	if s.mode&directives == 0 || s.ch != 'l' {
+	if s.mode&Directives == 0 || s.ch != 'l' {
 		s.stop()
 		s.skipComment()
 		return
@@ -804,7 +1015,9 @@ func (s *Scanner) escape(quote rune) bool {
 		if s.ch < 0 {
 			return true // complain in caller about fileOrEof
 		}
-		s.errorf("unknown escape")
+		// point at the backslash, which precedes s.ch
+		line, col := s.pos()
+		s.errh(line, col-1, fmt.Sprintf("unknown escape %q", s.ch))
 		return false
 	}
 
@@ -0,0 +1,454 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package scanner
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/token"
+	"strings"
+	"testing"
+)
+
+// scanMsgs scans src to the end in the given mode and returns the
+// messages passed to the error handler, prefixed by their position.
+func scanMsgs(src string, mode uint) []string {
+	var msgs []string
+	var s Scanner
+	s.InitMode(strings.NewReader(src), func(line, col uint, msg string) {
+		msgs = append(msgs, fmt.Sprintf("%d:%d: %s", line, col, msg))
+	}, mode)
+	for s.Next(); s.Token() != token.EOF; s.Next() {
+	}
+	return msgs
+}
+
+func TestTrailing(t *testing.T) {
+	const src = "a := 1  \n\tb\t\n// c  \n"
+
+	got := scanMsgs(src, Trailing)
+	want := []string{
+		"1:7: warning: trailing whitespace",
+		"2:3: warning: trailing whitespace",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// without the mode, nothing is reported
+	if got := scanMsgs(src, 0); len(got) != 0 {
+		t.Errorf("got %q, want no messages", got)
+	}
+}
+
+func TestCommentModes(t *testing.T) {
+	const src = "// plain\n//line foo.paw:10\nx /* full */ /*line bar.paw:1:1*/\n"
+	for _, test := range []struct {
+		mode uint
+		want []string
+	}{
+		{0, nil},
+		{Comments, []string{
+			"1:1: // plain",
+			"2:1: //line foo.paw:10",
+			"3:3: /* full */",
+			"3:14: /*line bar.paw:1:1*/",
+		}},
+		{Directives, []string{
+			"2:1: //line foo.paw:10",
+			"3:14: /*line bar.paw:1:1*/",
+		}},
+	} {
+		if got := scanMsgs(src, test.mode); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("mode %b: got %q, want %q", test.mode, got, test.want)
+		}
+	}
+}
+
+// tokens returns the tokens of src, each with its position and literal, if any.
+func tokens(src string) (list []string, errs []string) {
+	var s Scanner
+	s.Init(strings.NewReader(src), func(line, col uint, msg string) {
+		errs = append(errs, fmt.Sprintf("%d:%d: %s", line, col, msg))
+	})
+	for s.Next(); s.Token() != token.EOF; s.Next() {
+		tok := fmt.Sprintf("%d:%d: %s", s.Line(), s.Col(), s.Token())
+		if s.Token() == token.Name || s.Token() == token.Literal {
+			tok += fmt.Sprintf(" %q", s.Literal())
+		}
+		list = append(list, tok)
+	}
+	return
+}
+
+func TestMultiLineString(t *testing.T) {
+	for _, test := range []struct {
+		src        string
+		toks, errs []string
+	}{
+		{"x = \"\"\"a\n\"b\"\n\\\"\"\"\" y", []string{
+			"1:1: name \"x\"",
+			"1:3: =",
+			"1:5: Literal \"\\\"\\\"\\\"a\\n\\\"b\\\"\\n\\\\\\\"\\\"\\\"\\\"\"",
+			"3:7: name \"y\"",
+			"3:8: ;",
+		}, nil},
+		{"\"\"\"a\r\nb\"\"\"\r\ny", []string{
+			"1:1: Literal \"\\\"\\\"\\\"a\\nb\\\"\\\"\\\"\"",
+			"2:6: ;",
+			"3:1: name \"y\"",
+			"3:2: ;",
+		}, nil},
+		{"\"\" x", []string{
+			"1:1: Literal \"\\\"\\\"\"",
+			"1:4: name \"x\"",
+			"1:5: ;",
+		}, nil},
+		{"x \"\"\"a\nb", []string{
+			"1:1: name \"x\"",
+			"1:3: Literal \"\\\"\\\"\\\"a\\nb\"",
+			"2:2: ;",
+		}, []string{"1:3: string not terminated"}},
+	} {
+		toks, errs := tokens(test.src)
+		if fmt.Sprint(toks) != fmt.Sprint(test.toks) {
+			t.Errorf("%q: got tokens\n%s\nwant\n%s", test.src, strings.Join(toks, "\n"), strings.Join(test.toks, "\n"))
+		}
+		if fmt.Sprint(errs) != fmt.Sprint(test.errs) {
+			t.Errorf("%q: got errors %q, want %q", test.src, errs, test.errs)
+		}
+	}
+}
+
+func TestDots(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		toks []string
+	}{
+		{"a.", []string{`1:1: name "a"`, "1:2: ."}},
+		{"a..", []string{`1:1: name "a"`, "1:2: .."}},
+		{"a...", []string{`1:1: name "a"`, "1:2: ..."}},
+		{".5", []string{`1:1: Literal ".5"`, "1:3: ;"}},
+		{"..5", []string{"1:1: ..", `1:3: Literal "5"`, "1:4: ;"}},
+		{"...5", []string{"1:1: ...", `1:4: Literal "5"`, "1:5: ;"}},
+		{"1..5", []string{`1:1: Literal "1"`, "1:2: ..", `1:4: Literal "5"`, "1:5: ;"}},
+		{"1.5", []string{`1:1: Literal "1.5"`, "1:4: ;"}},
+	} {
+		toks, errs := tokens(test.src)
+		if fmt.Sprint(toks) != fmt.Sprint(test.toks) {
+			t.Errorf("%q: got %q, want %q", test.src, toks, test.toks)
+		}
+		if len(errs) != 0 {
+			t.Errorf("%q: got errors %q", test.src, errs)
+		}
+	}
+}
+
+func TestArrow(t *testing.T) {
+	for _, test := range []struct {
+		src string
+		tok token.Token
+		op  token.Operator
+	}{
+		{"<-", token.Arrow, token.NoneOp},
+		{"<<", token.Op, token.Shl},
+		{"<<=", token.AssignOp, token.Shl},
+		{"<=", token.Op, token.Leq},
+		{"<", token.Op, token.Lss},
+		{"< -", token.Op, token.Lss},
+	} {
+		var s Scanner
+		s.Init(strings.NewReader(test.src), func(line, col uint, msg string) {
+			t.Errorf("%q: %d:%d: %s", test.src, line, col, msg)
+		})
+		s.Next()
+		if s.Token() != test.tok || s.Op() != test.op {
+			t.Errorf("%q: got %s (%s), want %s (%s)", test.src, s.Token(), s.Op(), test.tok, test.op)
+		}
+	}
+
+	toks, _ := tokens("<-ch")
+	if want := []string{"1:1: <-", `1:3: name "ch"`, "1:5: ;"}; fmt.Sprint(toks) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", toks, want)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	for _, test := range []struct {
+		src    string
+		tok    token.Token
+		nlsemi bool // whether a newline after the keyword becomes ';'
+	}{
+		{"break", token.Break, true},
+		{"case", token.Case, false},
+		{"const", token.Const, false},
+		{"continue", token.Continue, true},
+		{"default", token.Default, false},
+		{"return", token.Return, true},
+		{"switch", token.Switch, false},
+		{"switched", token.Name, true},
+	} {
+		var s Scanner
+		s.Init(strings.NewReader(test.src+"\n"), func(line, col uint, msg string) {
+			t.Errorf("%q: %d:%d: %s", test.src, line, col, msg)
+		})
+		s.Next()
+		if s.Token() != test.tok {
+			t.Errorf("%q: got %s, want %s", test.src, s.Token(), test.tok)
+		}
+		s.Next()
+		if got := s.Token() == token.Semi; got != test.nlsemi {
+			t.Errorf("%q: got ';' after newline = %v, want %v", test.src, got, test.nlsemi)
+		}
+	}
+}
+
+func BenchmarkIdents(b *testing.B) {
+	var buf strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&buf, "var name%d = value + other * count\n", i)
+	}
+	src := buf.String()
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var s Scanner
+		s.Init(strings.NewReader(src), func(line, col uint, msg string) {
+			b.Fatalf("%d:%d: %s", line, col, msg)
+		})
+		for s.Next(); s.Token() != token.EOF; s.Next() {
+		}
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	const src = "space foo\n/* c */ x := \"héllo\" +\n\t12 // done\n"
+	var s Scanner
+	s.Init(strings.NewReader(src), func(line, col uint, msg string) {
+		t.Errorf("%d:%d: %s", line, col, msg)
+	})
+	var got []string
+	for s.Next(); s.Token() != token.EOF; s.Next() {
+		got = append(got, src[s.Offset():s.EndOffset()])
+	}
+	want := []string{"space", "foo", "\n", "x", ":=", `"héllo"`, "+", "12", "\n"}
+	if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOffsetsLargeInput(t *testing.T) {
+	// exceed the initial buffer size so that the buffer is refilled
+	src := strings.Repeat("abc ", 5000) + "last"
+	var s Scanner
+	s.Init(strings.NewReader(src), func(line, col uint, msg string) {
+		t.Errorf("%d:%d: %s", line, col, msg)
+	})
+	for s.Next(); s.Token() != token.EOF; s.Next() {
+		if s.Token() == token.Name && src[s.Offset():s.EndOffset()] != s.Literal() {
+			t.Fatalf("got %q at offset %d, want %q", src[s.Offset():s.EndOffset()], s.Offset(), s.Literal())
+		}
+	}
+}
+
+func TestBOM(t *testing.T) {
+	toks, errs := tokens("\uFEFFspace foo\n")
+	if want := []string{`1:1: space`, `1:7: name "foo"`, "1:10: ;"}; fmt.Sprint(toks) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", toks, want)
+	}
+	if len(errs) != 0 {
+		t.Errorf("got errors %q", errs)
+	}
+
+	// a BOM is only permitted as the very first character
+	_, errs = tokens("space \uFEFFfoo\n")
+	if want := []string{"1:7: invalid BOM in the middle of the file"}; fmt.Sprint(errs) != fmt.Sprint(want) {
+		t.Errorf("got errors %q, want %q", errs, want)
+	}
+	_, errs = tokens("\uFEFF\uFEFFspace foo\n")
+	if len(errs) != 1 {
+		t.Errorf("got errors %q, want one for the second BOM", errs)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	const src = "x := a..b\nreturn\n"
+	want, _ := tokens(src)
+
+	var s Scanner
+	s.Init(strings.NewReader(src), func(line, col uint, msg string) {
+		t.Errorf("%d:%d: %s", line, col, msg)
+	})
+	var got []string
+	s.Next()
+	for s.Token() != token.EOF {
+		tok := fmt.Sprintf("%d:%d: %s", s.Line(), s.Col(), s.Token())
+		if s.Token() == token.Name || s.Token() == token.Literal {
+			tok += fmt.Sprintf(" %q", s.Literal())
+		}
+		got = append(got, tok)
+
+		// peeking, even repeatedly, must not change the current token
+		next, lit := s.Peek()
+		if again, _ := s.Peek(); again != next {
+			t.Errorf("second Peek returned %s, want %s", again, next)
+		}
+		if cur := fmt.Sprintf("%d:%d: %s", s.Line(), s.Col(), s.Token()); !strings.HasPrefix(tok, cur) {
+			t.Errorf("current token changed to %s by Peek, want %s", cur, tok)
+		}
+
+		s.Next()
+		if s.Token() != next || s.Literal() != lit && (next == token.Name || next == token.Literal) {
+			t.Errorf("Next returned %s %q, Peek returned %s %q", s.Token(), s.Literal(), next, lit)
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnknownEscape(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want []string
+	}{
+		{`x := "\q"`, []string{`1:7: unknown escape 'q'`}},
+		{`x := "ab\ "`, []string{`1:9: unknown escape ' '`}},
+		{`x := '\d'`, []string{`1:7: unknown escape 'd'`}},
+		{"x := \"\"\"\n  \\d\"\"\"", []string{`2:3: unknown escape 'd'`}},
+		{`x := "\"`, []string{`1:6: string not terminated`}},
+	} {
+		got := scanMsgs(test.src, 0)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestHexFloatSeparators(t *testing.T) {
+	// invalidSep scans the whole literal, so separators in the
+	// exponent are checked like those in the mantissa.
+	for _, test := range []struct {
+		src  string
+		want []string
+	}{
+		{"x := 0x1p_1", []string{`1:10: '_' must separate successive digits`}},
+		{"x := 0x1p1_", []string{`1:11: '_' must separate successive digits`}},
+		{"x := 0x1.8p_3", []string{`1:12: '_' must separate successive digits`}},
+		{"x := 0x1p1__0", []string{`1:12: '_' must separate successive digits`}},
+		{"x := 0x_1p1", nil},
+		{"x := 0x1p1_0", nil},
+	} {
+		got := scanMsgs(test.src, 0)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestLongToken(t *testing.T) {
+	// exceed the size up to which the buffer is doubled
+	body := strings.Repeat("0123456789abcdef", 3<<16)
+	for _, src := range []string{`"` + body + `"`, "x" + body} {
+		var s Scanner
+		s.Init(strings.NewReader(src), func(line, col uint, msg string) {
+			t.Errorf("%d:%d: %s", line, col, msg)
+		})
+		s.Next()
+		if s.Literal() != src {
+			t.Errorf("got literal of length %d, want %d", len(s.Literal()), len(src))
+		}
+		if s.Offset() != 0 || s.EndOffset() != len(src) {
+			t.Errorf("got offsets %d:%d, want 0:%d", s.Offset(), s.EndOffset(), len(src))
+		}
+	}
+}
+
+func BenchmarkScanLongToken(b *testing.B) {
+	src := `"` + strings.Repeat("x", 1<<20) + `"`
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var s Scanner
+		s.Init(strings.NewReader(src), func(line, col uint, msg string) {
+			b.Fatalf("%d:%d: %s", line, col, msg)
+		})
+		s.Next()
+		if len(s.Literal()) != len(src) {
+			b.Fatalf("got literal of length %d, want %d", len(s.Literal()), len(src))
+		}
+	}
+}
+
+func TestLoneCarriageReturn(t *testing.T) {
+	// A lone '\r' ends a line like '\n' and "\r\n" do.
+	toks, errs := tokens("x\ry // c\rz\r\n\"\"\"a\rb\"\"\"\rw")
+	want := []string{
+		`1:1: name "x"`,
+		"1:2: ;",
+		`2:1: name "y"`,
+		"2:7: ;",
+		`3:1: name "z"`,
+		"3:3: ;",
+		`4:1: Literal "\"\"\"a\nb\"\"\""`,
+		"5:5: ;",
+		`6:1: name "w"`,
+		"6:2: ;",
+	}
+	if fmt.Sprint(toks) != fmt.Sprint(want) {
+		t.Errorf("got tokens\n%s\nwant\n%s", strings.Join(toks, "\n"), strings.Join(want, "\n"))
+	}
+	if len(errs) != 0 {
+		t.Errorf("got errors %q", errs)
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	// scan returns the tokens of src, with their literals if any
+	scan := func(src string, mode uint) []string {
+		var toks []string
+		var s Scanner
+		s.InitMode(strings.NewReader(src), func(line, col uint, msg string) {
+			t.Errorf("%q: %d:%d: %s", src, line, col, msg)
+		}, mode)
+		for s.Next(); s.Token() != token.EOF; s.Next() {
+			switch s.Token() {
+			case token.Name, token.Literal, token.StringHead, token.StringMid, token.StringTail:
+				toks = append(toks, s.Token().String()+" "+s.Literal())
+			case token.Semi:
+			default:
+				toks = append(toks, s.Token().String())
+			}
+		}
+		return toks
+	}
+
+	for _, test := range []struct {
+		src  string
+		want []string
+	}{
+		{`"a${x}b"`, []string{`string head "a${`, `name x`, `string tail }b"`}},
+		{`"${x}${y}"`, []string{`string head "${`, `name x`, `string middle }${`, `name y`, `string tail }"`}},
+		{`"$x $ {y} \${"`, []string{`Literal "$x $ {y} \${"`}},
+		{`"${T{1}.f + "${y}"}!"`, []string{
+			`string head "${`, `name T`, `{`, `Literal 1`, `}`, `.`, `name f`, `op`,
+			`string head "${`, `name y`, `string tail }"`,
+			`string tail }!"`,
+		}},
+	} {
+		if got := scan(test.src, Interpolation); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: got %q, want %q", test.src, got, test.want)
+		}
+	}
+
+	// by default, $ is an ordinary character
+	if got, want := scan(`"a${x}b"`, 0), []string{`Literal "a${x}b"`}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("without the mode: got %q, want %q", got, want)
+	}
+}
@@ -50,6 +50,7 @@ type source struct {
 	buf       []byte // source buffer
 	ioerr     error  // pending I/O error, or nil
 	b, r, e   int    // buffer indices (see comment above)
+	base      int    // source offset of buf[0]
 	line, col uint   // source position of ch (0-based)
 	ch        rune   // most recently read character
 	chw       int    // width of ch
@@ -67,6 +68,7 @@ func (s *source) init(in io.Reader, errh func(line, col uint, msg string)) {
 	s.buf[0] = sentinel
 	s.ioerr = nil
 	s.b, s.r, s.e = -1, 0, 0
+	s.base = 0
 	s.line, s.col = 0, 0
 	s.ch = ' '
 	s.chw = 0
@@ -81,6 +83,11 @@ func (s *source) pos() (line, col uint) {
 	return linebase + s.line, colbase + s.col
 }
 
+// offset returns the byte offset of s.ch in the source.
+func (s *source) offset() int {
+	return s.base + s.r - s.chw
+}
+
 // error reports the error msg at source position s.pos().
 func (s *source) error(msg string) {
 	line, col := s.pos()
@@ -94,20 +101,16 @@ func (s *source) start()          { s.b = s.r - s.chw }
 func (s *source) stop()           { s.b = -1 }
 func (s *source) Segment() []byte { return s.buf[s.b : s.r-s.chw] }
 
-// rewind rewinds the scanner's read position and character s.ch
-// to the start of the currently active Segment, which must not
-// contain any newlines (otherwise position information will be
-// incorrect). Currently, rewind is only needed for handling the
-// source sequence ".."; it must not be called outside an active
-// Segment.
-func (s *source) rewind() {
-	// ok to verify precondition - rewind is rarely called
-	if s.b < 0 {
-		panic("no active segment")
+// peek returns the byte immediately following s.ch without
+// consuming it, or -1 if there is none.
+func (s *source) peek() int {
+	for s.r == s.e && s.ioerr == nil {
+		s.fill()
 	}
-	s.col -= uint(s.r - s.b)
-	s.r = s.b
-	s.nextch()
+	if s.r == s.e {
+		return -1
+	}
+	return int(s.buf[s.r])
 }
 
 func (s *source) nextch() {
@@ -126,6 +129,11 @@ redo:
 			s.error("invalid NUL character")
 			goto redo
 		}
+		if s.ch == '\r' && s.peek() != '\n' {
+			// a lone carriage return (old Mac line ending)
+			// ends the line like a newline
+			s.ch = '\n'
+		}
 		return
 	}
 
@@ -157,9 +165,11 @@ redo:
 	// BOM's are only allowed as the first character in a file
 	const BOM = 0xfeff
 	if s.ch == BOM {
-		if s.line > 0 || s.col > 0 {
+		if s.offset() > 0 {
 			s.error("invalid BOM in the middle of the file")
+			goto redo
 		}
+		s.chw = 0 // a leading BOM does not count towards the column
 		goto redo
 	}
 }
@@ -184,6 +194,7 @@ func (s *source) fill() {
 	}
 	s.r -= b
 	s.e -= b
+	s.base += b
 
 	// read more data: try a limited number of times
 	for i := 0; i < 10; i++ {
@@ -214,5 +225,7 @@ func nextSize(size int) int {
 	if size <= max {
 		return size << 1
 	}
-	return size + max
+	// keep growing geometrically so that very long tokens
+	// are not copied a number of times linear in their size
+	return size + size>>1
 }
@@ -0,0 +1,43 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package sema
+
+import "jindo/pkg/jindo/ast"
+
+// UnusedImports reports the imports of the files of a space which no
+// identifier resolved by Resolve, as recorded in info, refers to. Each
+// error is reported at the import path.
+func UnusedImports(files []*ast.File, info *Info) []Error {
+	used := make(map[ast.Decl]bool)
+	for _, obj := range info.Uses {
+		if obj.Kind == SpaceObj {
+			if d, ok := obj.Decl.(ast.Decl); ok {
+				used[d] = true
+			}
+		}
+	}
+
+	var errors []Error
+	for _, f := range files {
+		for _, d := range f.DeclList {
+			d, ok := d.(*ast.ImportDecl)
+			if !ok || used[d] || d.Path == nil || d.Path.Bad {
+				continue
+			}
+			name := ImportName(d)
+			if name == "" || name == "_" {
+				continue // malformed, or imported for its side effects
+			}
+			msg := d.Path.Value + " imported and not used"
+			if d.LocalName != nil {
+				msg = d.Path.Value + " imported as " + name + " and not used"
+			}
+			errors = append(errors, Error{d.Path.Pos, msg})
+		}
+	}
+	return errors
+}
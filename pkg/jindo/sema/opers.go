@@ -0,0 +1,89 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package sema
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/token"
+)
+
+// CheckOpers verifies that the reversed operator declarations of a space
+// are consistent with the forward ones. The receiver of a reversed
+// operator such as radd is its right operand, so
+//
+//	oper (t T) radd (u U) R
+//
+// implements u + t. Such a declaration is reported if its operand has
+// the same type as the receiver, since add on T then applies as well,
+// and if U itself declares the forward operator for u + t, as in
+//
+//	oper (u U) add (t T) R
+//
+// which leaves the expression ambiguous.
+func CheckOpers(files []*ast.File) []Error {
+	type pair struct {
+		op   token.Operator // forward operator
+		l, r string         // operand types
+	}
+	forward := make(map[pair]*ast.OperDecl)
+	var reversed []*ast.OperDecl
+	for _, f := range files {
+		for _, d := range f.DeclList {
+			d, ok := d.(*ast.OperDecl)
+			if !ok {
+				continue
+			}
+			if d.Oper.IsReversed() {
+				reversed = append(reversed, d)
+				continue
+			}
+			l, r := operandType(d.TypeL), operandType(d.TypeR)
+			if l != "" && r != "" {
+				if _, ok := forward[pair{d.Oper, l, r}]; !ok {
+					forward[pair{d.Oper, l, r}] = d
+				}
+			}
+		}
+	}
+
+	var errors []Error
+	for _, d := range reversed {
+		recv, x := operandType(d.TypeL), operandType(d.TypeR)
+		if recv == "" || x == "" {
+			continue
+		}
+		name, fwd := d.Oper.OverloadName(), d.Oper.Forward().OverloadName()
+		if recv == x {
+			msg := fmt.Sprintf("reversed operator %s has operands of the same type %s; declare %s instead", name, recv, fwd)
+			errors = append(errors, Error{d.TypeR.Type.GetPos(), msg})
+			continue
+		}
+		if alt := forward[pair{d.Oper.Forward(), x, recv}]; alt != nil {
+			msg := fmt.Sprintf("reversed operator %s on %s conflicts with %s on %s at %s", name, recv, fwd, x, alt.Pos)
+			errors = append(errors, Error{d.Pos, msg})
+		}
+	}
+	return errors
+}
+
+// operandType returns the name of the type of an operand, or "" if it
+// is missing or not a (qualified) type name.
+func operandType(f *ast.Field) string {
+	if f == nil {
+		return ""
+	}
+	switch t := unparen(f.Type).(type) {
+	case *ast.Name:
+		return t.Value
+	case *ast.SelectorExpr:
+		if x, ok := unparen(t.X).(*ast.Name); ok && t.Sel != nil {
+			return x.Value + "." + t.Sel.Value
+		}
+	}
+	return ""
+}
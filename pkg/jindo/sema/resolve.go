@@ -0,0 +1,407 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package sema
+
+import (
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/token"
+)
+
+// Info holds the result of resolving a space.
+type Info struct {
+	// Defs maps identifiers to the objects they declare.
+	Defs map[*ast.Name]*Object
+
+	// Uses maps identifiers to the objects they denote.
+	// Identifiers which could not be resolved are absent.
+	Uses map[*ast.Name]*Object
+
+	// Space is the scope holding the space-level declarations.
+	// Its parent is Universe.
+	Space *Scope
+
+	// Opers maps each operator overloaded in the space,
+	// reversed or not, to its declarations.
+	Opers map[token.Operator][]*ast.OperDecl
+}
+
+// IsConversion reports whether call is a type conversion T(x) rather than
+// a function call, that is whether call.Func resolves to a type.
+func (info *Info) IsConversion(call *ast.CallExpr) bool {
+	switch f := unparen(call.Func).(type) {
+	case *ast.Name:
+		obj := info.Uses[f]
+		return obj != nil && obj.Kind == TypeObj
+	case *ast.SliceType:
+		return true
+	}
+	return false
+}
+
+// IsOperCall reports whether call has the form x.op(y) where op names an
+// operator overloaded in the space, as in a.add(b). Such a call dispatches
+// to an oper declaration rather than to a method or a function of an
+// imported space.
+func (info *Info) IsOperCall(call *ast.CallExpr) bool {
+	sel, ok := unparen(call.Func).(*ast.SelectorExpr)
+	if !ok || len(call.ArgList) != 1 {
+		return false
+	}
+	if x, ok := unparen(sel.X).(*ast.Name); ok {
+		if obj := info.Uses[x]; obj != nil && obj.Kind == SpaceObj {
+			return false
+		}
+	}
+	op := token.OperOrNil(sel.Sel.Value)
+	return op.IsOperOverload() && len(info.Opers[op]) > 0
+}
+
+func unparen(x ast.Expr) ast.Expr {
+	for {
+		p, ok := x.(*ast.ParenExpr)
+		if !ok {
+			return x
+		}
+		x = p.X
+	}
+}
+
+// Resolve binds every identifier in the files of a space to the object it
+// denotes. Names are looked up in the enclosing block scopes, then in the
+// file scope holding the imports, then in the space scope, and finally in
+// Universe. Undefined and redeclared names are reported as errors.
+func Resolve(files []*ast.File) (*Info, []Error) {
+	r := resolver{
+		info: &Info{
+			Defs:  make(map[*ast.Name]*Object),
+			Uses:  make(map[*ast.Name]*Object),
+			Space: NewScope(Universe),
+			Opers: make(map[token.Operator][]*ast.OperDecl),
+		},
+	}
+
+	// collect space-level declarations first so that
+	// they may be referred to before they are declared
+	for _, f := range files {
+		for _, d := range f.DeclList {
+			switch d := d.(type) {
+			case *ast.TypeDecl:
+				r.declare(r.info.Space, TypeObj, d.Name, d)
+			case *ast.ConstDecl:
+				r.declare(r.info.Space, ConstObj, d.NameList, d)
+			case *ast.VarDecl:
+				for _, name := range d.NameList {
+					r.declare(r.info.Space, VarObj, name, d)
+				}
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					// methods are looked up through their receiver
+					r.declare(r.info.Space, FuncObj, d.Name, d)
+				}
+			case *ast.OperDecl:
+				r.info.Opers[d.Oper] = append(r.info.Opers[d.Oper], d)
+			}
+		}
+	}
+
+	for _, f := range files {
+		r.file(f)
+	}
+	return r.info, r.errors
+}
+
+type resolver struct {
+	info   *Info
+	scope  *Scope // current scope
+	errors []Error
+}
+
+func (r *resolver) errorf(at ast.Node, msg string) {
+	r.errors = append(r.errors, Error{at.GetPos(), msg})
+}
+
+func (r *resolver) declare(s *Scope, kind ObjKind, name *ast.Name, decl ast.Node) {
+	if name == nil || name.Value == "_" {
+		return
+	}
+	obj := &Object{Kind: kind, Name: name.Value, Decl: decl}
+	if alt := s.Insert(obj); alt != nil {
+		r.errorf(name, name.Value+" redeclared in this block")
+		return
+	}
+	r.info.Defs[name] = obj
+}
+
+func (r *resolver) openScope()  { r.scope = NewScope(r.scope) }
+func (r *resolver) closeScope() { r.scope = r.scope.Parent() }
+
+func (r *resolver) file(f *ast.File) {
+	r.scope = NewScope(r.info.Space)
+	for _, d := range f.DeclList {
+		if d, ok := d.(*ast.ImportDecl); ok {
+			if name := ImportName(d); name != "" {
+				r.scope.Insert(&Object{Kind: SpaceObj, Name: name, Decl: d})
+			}
+		}
+	}
+
+	for _, d := range f.DeclList {
+		r.decl(d, false)
+	}
+	r.scope = nil
+}
+
+// decl resolves d. If local is set, the names declared by d are
+// added to the current scope after resolving its type and values.
+func (r *resolver) decl(d ast.Decl, local bool) {
+	switch d := d.(type) {
+	case *ast.TypeDecl:
+		r.expr(d.Type)
+		if local {
+			r.declare(r.scope, TypeObj, d.Name, d)
+		}
+
+	case *ast.ConstDecl:
+		r.expr(d.Type)
+		r.expr(d.Values)
+		if local {
+			r.declare(r.scope, ConstObj, d.NameList, d)
+		}
+
+	case *ast.VarDecl:
+		r.expr(d.Type)
+		r.expr(d.Values)
+		if local {
+			for _, name := range d.NameList {
+				r.declare(r.scope, VarObj, name, d)
+			}
+		}
+
+	case *ast.FuncDecl:
+		r.openScope()
+		if f := d.Recv; f != nil {
+			r.expr(f.Type)
+			r.declare(r.scope, VarObj, f.Name, f)
+		}
+		for _, f := range d.Param {
+			r.expr(f.Type)
+			r.declare(r.scope, VarObj, f.Name, f)
+		}
+		r.expr(d.Return)
+		r.funcBody(d.Body)
+		r.closeScope()
+
+	case *ast.OperDecl:
+		r.openScope()
+		for _, f := range []*ast.Field{d.TypeL, d.TypeR} {
+			if f != nil {
+				r.expr(f.Type)
+				r.declare(r.scope, VarObj, f.Name, f)
+			}
+		}
+		r.expr(d.Return)
+		r.funcBody(d.Body)
+		r.closeScope()
+	}
+}
+
+// funcBody resolves the statements of b in the current
+// scope, which also holds the function parameters.
+func (r *resolver) funcBody(b *ast.BlockStmt) {
+	if b != nil {
+		r.stmtList(b.StmtList)
+	}
+}
+
+func (r *resolver) stmtList(list []ast.Stmt) {
+	for _, s := range list {
+		r.stmt(s)
+	}
+}
+
+func (r *resolver) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case nil, *ast.EmptyStmt, *ast.BreakStmt, *ast.ContinueStmt, *ast.GotoStmt:
+		// nothing to do; labels are not identifiers of a scope
+
+	case *ast.LabeledStmt:
+		r.stmt(s.Stmt)
+
+	case *ast.ExprStmt:
+		r.expr(s.X)
+
+	case *ast.IncDecStmt:
+		r.expr(s.X)
+
+	case *ast.AssignStmt:
+		r.exprList(s.Lhs)
+		r.exprList(s.Rhs)
+
+	case *ast.DefineStmt:
+		// at least one name must be new in the current scope;
+		// the others are assigned to
+		r.exprList(s.Rhs)
+		isNew := false
+		for _, x := range s.Lhs {
+			name, ok := x.(*ast.Name)
+			if !ok {
+				r.errorf(x, "non-name on left side of :=")
+				isNew = true // don't also report no new variables
+				continue
+			}
+			if obj := r.scope.elems[name.Value]; obj != nil {
+				if obj.Decl == s {
+					r.errorf(name, name.Value+" repeated on left side of :=")
+				} else {
+					r.info.Uses[name] = obj
+				}
+				continue
+			}
+			r.declare(r.scope, VarObj, name, s)
+			isNew = true
+		}
+		if !isNew {
+			r.errorf(s, "no new variables on left side of :=")
+		}
+
+	case *ast.ReturnStmt:
+		r.expr(s.Result)
+
+	case *ast.DeclStmt:
+		for _, d := range s.DeclList {
+			r.decl(d, true)
+		}
+
+	case *ast.BlockStmt:
+		r.openScope()
+		r.stmtList(s.StmtList)
+		r.closeScope()
+
+	case *ast.IfStmt:
+		r.openScope()
+		r.expr(s.Cond)
+		r.stmt(s.Block)
+		r.stmt(s.Else)
+		r.closeScope()
+
+	case *ast.ForStmt:
+		r.openScope()
+		r.stmt(s.Init)
+		r.expr(s.Cond)
+		r.stmt(s.Post)
+		r.stmt(s.Body)
+		r.closeScope()
+
+	case *ast.WhileStmt:
+		r.openScope()
+		r.expr(s.Cond)
+		r.stmt(s.Body)
+		r.closeScope()
+
+	case *ast.SwitchStmt:
+		r.openScope()
+		r.stmt(s.Init)
+		r.expr(s.Tag)
+		for _, c := range s.Body {
+			r.exprList(c.CaseList)
+			r.openScope()
+			r.stmtList(c.Body)
+			r.closeScope()
+		}
+		r.closeScope()
+	}
+}
+
+func (r *resolver) expr(x ast.Expr) {
+	switch x := x.(type) {
+	case nil, *ast.BadExpr, *ast.BasicLit:
+		// nothing to do
+
+	case *ast.Name:
+		if x.Value == "_" {
+			return
+		}
+		if obj := r.scope.Lookup(x.Value); obj != nil {
+			r.info.Uses[x] = obj
+			return
+		}
+		r.errorf(x, "undefined: "+x.Value)
+
+	case *ast.SliceLit:
+		r.expr(x.ElemType)
+		for _, e := range x.Elems {
+			r.expr(e)
+		}
+
+	case *ast.CompositeLit:
+		r.expr(x.Type)
+		for _, e := range x.ElemList {
+			if kv, ok := e.(*ast.KeyValueExpr); ok {
+				// keys name struct fields and are resolved once types are known
+				if _, ok := kv.Key.(*ast.Name); !ok {
+					r.expr(kv.Key)
+				}
+				e = kv.Value
+			}
+			r.expr(e)
+		}
+
+	case *ast.Operation:
+		r.expr(x.X)
+		r.expr(x.Y)
+
+	case *ast.ParenExpr:
+		r.expr(x.X)
+
+	case *ast.ListExpr:
+		r.exprList(x.ElemList)
+
+	case *ast.SliceType:
+		r.expr(x.Elem)
+
+	case *ast.MapType:
+		r.expr(x.Key)
+		r.expr(x.Value)
+
+	case *ast.PointerType:
+		r.expr(x.Elem)
+
+	case *ast.DotsType:
+		r.expr(x.Elem)
+
+	case *ast.StructType:
+		for _, f := range x.FieldList {
+			r.expr(f.Type)
+		}
+
+	case *ast.SelectorExpr:
+		// the selector is resolved once types are known
+		r.expr(x.X)
+
+	case *ast.IndexExpr:
+		r.expr(x.X)
+		r.expr(x.Index)
+
+	case *ast.SliceExpr:
+		r.expr(x.X)
+		r.expr(x.Low)
+		r.expr(x.High)
+		r.expr(x.Max)
+
+	case *ast.CallExpr:
+		r.expr(x.Func)
+		for _, a := range x.ArgList {
+			r.expr(a)
+		}
+	}
+}
+
+func (r *resolver) exprList(list []ast.Expr) {
+	for _, x := range list {
+		r.expr(x)
+	}
+}
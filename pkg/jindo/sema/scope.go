@@ -0,0 +1,80 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package sema
+
+import "jindo/pkg/jindo/ast"
+
+// ObjKind describes what an Object denotes.
+type ObjKind uint8
+
+const (
+	BadObj     ObjKind = iota
+	TypeObj            // type name
+	VarObj             // variable or parameter
+	ConstObj           // constant
+	FuncObj            // function
+	BuiltinObj         // predeclared function
+	NilObj             // predeclared nil
+	SpaceObj           // imported space
+)
+
+var objKindString = [...]string{
+	BadObj:     "bad",
+	TypeObj:    "type",
+	VarObj:     "var",
+	ConstObj:   "const",
+	FuncObj:    "func",
+	BuiltinObj: "builtin",
+	NilObj:     "nil",
+	SpaceObj:   "space",
+}
+
+func (k ObjKind) String() string { return objKindString[k] }
+
+// An Object is a named language entity such as a type,
+// variable, function or imported space.
+type Object struct {
+	Kind ObjKind
+	Name string
+	Decl ast.Node // declaring node; nil for predeclared objects
+}
+
+// A Scope maps names to the objects declared in it.
+type Scope struct {
+	parent *Scope
+	elems  map[string]*Object
+}
+
+// NewScope returns a new, empty scope nested in parent.
+func NewScope(parent *Scope) *Scope {
+	return &Scope{parent, make(map[string]*Object)}
+}
+
+// Parent returns the scope enclosing s, or nil for the universe.
+func (s *Scope) Parent() *Scope { return s.parent }
+
+// Lookup returns the object with the given name in s or the innermost
+// enclosing scope declaring it, or nil if there is no such object.
+func (s *Scope) Lookup(name string) *Object {
+	for ; s != nil; s = s.parent {
+		if obj := s.elems[name]; obj != nil {
+			return obj
+		}
+	}
+	return nil
+}
+
+// Insert declares obj in s. If s already declares an object with the
+// same name, Insert leaves s unchanged and returns that object;
+// otherwise it returns nil.
+func (s *Scope) Insert(obj *Object) *Object {
+	if alt := s.elems[obj.Name]; alt != nil {
+		return alt
+	}
+	s.elems[obj.Name] = obj
+	return nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+// Package sema implements the semantic passes run over the
+// syntax trees of a space after parsing.
+package sema
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/position"
+)
+
+// Error describes a semantic error. Error implements the error interface.
+type Error struct {
+	Pos position.Pos
+	Msg string
+}
+
+func (err Error) Error() string {
+	return fmt.Sprintf("%s: %s", err.Pos, err.Msg)
+}
+
+var _ error = Error{} // verify that Error implements error
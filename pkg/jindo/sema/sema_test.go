@@ -0,0 +1,253 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package sema
+
+import (
+	"fmt"
+	"jindo/pkg/jindo/ast"
+	"jindo/pkg/jindo/parser"
+	"jindo/pkg/jindo/position"
+	"strings"
+	"testing"
+)
+
+func parseString(t *testing.T, src string) *ast.File {
+	t.Helper()
+	f, err := parser.Parse(position.NewFileBase("test.jindo"), strings.NewReader(src), func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCheckTypes(t *testing.T) {
+	f := parseString(t, "space main\ntype T int\ntype S []T\nvar v T\nfunc f(a T, b string) int {\n\tvar x T\n\treturn a\n}\n")
+	if errs := CheckTypes([]*ast.File{f}); len(errs) != 0 {
+		t.Errorf("got unexpected errors %v", errs)
+	}
+
+	f = parseString(t, "space main\nfunc f(a Missing) int {\n\treturn 0\n}\n")
+	errs := CheckTypes([]*ast.File{f})
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+	}
+	if got, want := errs[0].Msg, "undefined type: Missing"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := errs[0].Pos.String(); got != "test.jindo:2:10" {
+		t.Errorf("got position %s, want test.jindo:2:10", got)
+	}
+}
+
+func TestUniverse(t *testing.T) {
+	f := parseString(t, "space main\nfunc f(s string) int {\n\treturn len(s) + int(y)\n}\n")
+	info, errs := Resolve([]*ast.File{f})
+
+	uses := make(map[string]*Object)
+	for name, obj := range info.Uses {
+		uses[name.Value] = obj
+	}
+	for _, name := range []string{"int", "len", "string"} {
+		if obj := uses[name]; obj == nil || obj != Universe.Lookup(name) {
+			t.Errorf("%s resolved to %v, want universe entry", name, obj)
+		}
+	}
+	if obj := uses["s"]; obj == nil || obj.Kind != VarObj {
+		t.Errorf("s resolved to %v, want parameter", obj)
+	}
+	if obj, ok := uses["y"]; ok {
+		t.Errorf("y resolved to %v, want unresolved", obj)
+	}
+	if len(errs) != 1 || errs[0].Msg != "undefined: y" {
+		t.Errorf("got errors %v, want undefined: y", errs)
+	}
+}
+
+func TestIsConversion(t *testing.T) {
+	f := parseString(t, "space main\nfunc g(x int) int {\n\treturn x\n}\nfunc f(x int) {\n\ta := int(x)\n\tb := g(x)\n}\n")
+	info, errs := Resolve([]*ast.File{f})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected errors %v", errs)
+	}
+
+	body := f.DeclList[1].(*ast.FuncDecl).Body
+	for i, want := range []bool{true, false} {
+		call := body.StmtList[i].(*ast.DefineStmt).Rhs[0].(*ast.CallExpr)
+		if got := info.IsConversion(call); got != want {
+			t.Errorf("IsConversion(%s) = %v, want %v", call.Func.(*ast.Name).Value, got, want)
+		}
+	}
+}
+
+func TestOperCallChain(t *testing.T) {
+	f := parseString(t, "space main\nimport \"vec\"\ntype T int\noper (a T) add (b T) T {\n\treturn a\n}\noper (a T) mul (b T) T {\n\treturn a\n}\nfunc f(a T, b T, c T) {\n\tx := a.add(b).mul(c).sub(a)\n\ty := vec.add(a)\n}\n")
+	info, errs := Resolve([]*ast.File{f})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected errors %v", errs)
+	}
+
+	// x := ((a.add(b)).mul(c)).sub(a)
+	body := f.DeclList[4].(*ast.FuncDecl).Body
+	call := body.StmtList[0].(*ast.DefineStmt).Rhs[0].(*ast.CallExpr)
+	for _, want := range []struct {
+		sel  string
+		arg  string
+		oper bool
+	}{
+		{"sub", "a", false}, // not overloaded in the space
+		{"mul", "c", true},
+		{"add", "b", true},
+	} {
+		sel, ok := call.Func.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Value != want.sel || len(call.ArgList) != 1 || call.ArgList[0].(*ast.Name).Value != want.arg {
+			t.Fatalf("got call %v, want .%s(%s)", call.Func, want.sel, want.arg)
+		}
+		if got := info.IsOperCall(call); got != want.oper {
+			t.Errorf("IsOperCall(.%s) = %v, want %v", want.sel, got, want.oper)
+		}
+		if obj := info.Uses[call.ArgList[0].(*ast.Name)]; obj == nil || obj.Kind != VarObj {
+			t.Errorf(".%s: argument resolved to %v, want a parameter", want.sel, obj)
+		}
+		if x, ok := sel.X.(*ast.CallExpr); ok {
+			call = x
+			continue
+		}
+		if x, ok := sel.X.(*ast.Name); !ok || x.Value != "a" || info.Uses[x] == nil {
+			t.Errorf("got receiver %v, want a resolved a", sel.X)
+		}
+	}
+
+	// a call into an imported space is not an operator call
+	call = body.StmtList[1].(*ast.DefineStmt).Rhs[0].(*ast.CallExpr)
+	if info.IsOperCall(call) {
+		t.Error("IsOperCall(vec.add) = true, want false")
+	}
+}
+
+func TestForwardReferences(t *testing.T) {
+	f := parseString(t, "space main\nfunc a() int {\n\treturn b() + c\n}\nfunc b() int {\n\treturn 1\n}\n")
+	g := parseString(t, "space main\nvar c T\ntype T int\n")
+	info, errs := Resolve([]*ast.File{f, g})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected errors %v", errs)
+	}
+
+	// return b() + c
+	ret := f.DeclList[0].(*ast.FuncDecl).Body.StmtList[0].(*ast.ReturnStmt).Result.(*ast.Operation)
+	b := ret.X.(*ast.CallExpr).Func.(*ast.Name)
+	if obj := info.Uses[b]; obj == nil || obj.Kind != FuncObj || obj.Decl != f.DeclList[1] {
+		t.Errorf("b resolved to %v, want the later func b", obj)
+	}
+	if obj := info.Uses[ret.Y.(*ast.Name)]; obj == nil || obj.Kind != VarObj || obj.Decl != g.DeclList[0] {
+		t.Errorf("c resolved to %v, want var c of the second file", obj)
+	}
+	if obj := info.Uses[g.DeclList[0].(*ast.VarDecl).Type.(*ast.Name)]; obj == nil || obj.Kind != TypeObj {
+		t.Errorf("T resolved to %v, want the later type T", obj)
+	}
+}
+
+func TestResolveDefine(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\ta, err := 1, 2\n\tb, err := 3, 4\n\treturn a + b + err\n}\n")
+	info, errs := Resolve([]*ast.File{f})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected errors %v", errs)
+	}
+	body := f.DeclList[0].(*ast.FuncDecl).Body.StmtList
+	first, second := body[0].(*ast.DefineStmt), body[1].(*ast.DefineStmt)
+	err1, err2 := first.Lhs[1].(*ast.Name), second.Lhs[1].(*ast.Name)
+	if info.Defs[err1] == nil || info.Defs[err2] != nil || info.Uses[err2] != info.Defs[err1] {
+		t.Errorf("second err: got def %v and use %v, want a use of the first err", info.Defs[err2], info.Uses[err2])
+	}
+	if info.Defs[second.Lhs[0].(*ast.Name)] == nil {
+		t.Errorf("b: got no definition")
+	}
+
+	for _, test := range []struct {
+		stmts, want string
+	}{
+		{"a := 1\n\ta := 2", "4:4: no new variables on left side of :="},
+		{"a, a := 1, 2", "3:5: a repeated on left side of :="},
+		// a while loop has its own scope, like if and for
+		{"a := 1\n\twhile true {\n\t\ta := 2\n\t}", ""},
+	} {
+		f := parseString(t, "space main\nfunc f() {\n\t"+test.stmts+"\n}\n")
+		var got []string
+		_, errs := Resolve([]*ast.File{f})
+		for _, err := range errs {
+			got = append(got, strings.TrimPrefix(err.Error(), "test.jindo:"))
+		}
+		if strings.Join(got, "; ") != test.want {
+			t.Errorf("%q: got errors %q, want %q", test.stmts, got, test.want)
+		}
+	}
+}
+
+func TestCheckOpers(t *testing.T) {
+	f := parseString(t, "space main\ntype T int\ntype U int\noper (a T) add (b T) T {\n\treturn a\n}\noper (a T) radd (b int) T {\n\treturn a\n}\noper (a T) rsub (b U) T {\n\treturn a\n}\noper (a U) add (b T) T {\n\treturn b\n}\n")
+	if errs := CheckOpers([]*ast.File{f}); len(errs) != 0 {
+		t.Errorf("got unexpected errors %v", errs)
+	}
+
+	g := parseString(t, "space main\noper (a T) rmul (b T) T {\n\treturn a\n}\noper (a T) radd (b U) T {\n\treturn a\n}\n")
+	errs := CheckOpers([]*ast.File{f, g})
+	want := []string{
+		"test.jindo:2:20: reversed operator rmul has operands of the same type T; declare mul instead",
+		"test.jindo:5:6: reversed operator radd on T conflicts with add on U at test.jindo:13:6",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("got %q, want %q", err.Error(), want[i])
+		}
+	}
+}
+
+func TestCheckLocalTypes(t *testing.T) {
+	f := parseString(t, "space main\nfunc f() {\n\ttype T int\n\tvar x T\n\tif x {\n\t\ttype S []T\n\t\tvar s S\n\t}\n\tvar s S\n}\n")
+	errs := CheckTypes([]*ast.File{f})
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+	}
+	if got, want := errs[0].Error(), "test.jindo:9:8: undefined type: S"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnusedImports(t *testing.T) {
+	a := parseString(t, "space main\nimport \"io\"\nimport fm \"fmt\"\nimport \"geom/vec\"\nimport _ \"init\"\nvar w io.Writer\n")
+	b := parseString(t, "space main\nimport \"fmt\"\nimport \"geom/vec\"\nfunc f() {\n\tfmt.Println(vec.Zero)\n}\n")
+	info, errs := Resolve([]*ast.File{a, b})
+	if len(errs) != 0 {
+		t.Fatalf("got unexpected errors %v", errs)
+	}
+
+	errs = UnusedImports([]*ast.File{a, b}, info)
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`test.jindo:3:11: "fmt" imported as fm and not used`,
+		`test.jindo:4:8: "geom/vec" imported and not used`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if errs := UnusedImports([]*ast.File{b}, info); len(errs) != 0 {
+		t.Errorf("got unexpected errors %v", errs)
+	}
+
+	// an import without a path is a syntax error, not an unused import
+	c, _ := parser.Parse(position.NewFileBase("test.jindo"), strings.NewReader("space main\nimport foo\n"), func(error) {})
+	info, _ = Resolve([]*ast.File{c})
+	if errs := UnusedImports([]*ast.File{c}, info); len(errs) != 0 {
+		t.Errorf("got unexpected errors %v", errs)
+	}
+}
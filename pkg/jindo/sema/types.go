@@ -0,0 +1,188 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package sema
+
+import (
+	"jindo/pkg/jindo/ast"
+	"path"
+	"strconv"
+)
+
+// CheckTypes verifies that every type name used in the declarations and
+// signatures of a space refers to a type declared in the space, to an
+// imported space, or to a predeclared type in Universe. This is a presence check
+// only; it does not type check expressions.
+func CheckTypes(files []*ast.File) []Error {
+	c := typeChecker{declared: make(map[string]bool)}
+	for _, f := range files {
+		for _, d := range f.DeclList {
+			if d, ok := d.(*ast.TypeDecl); ok {
+				c.declared[d.Name.Value] = true
+			}
+		}
+	}
+	for _, f := range files {
+		c.file(f)
+	}
+	return c.errors
+}
+
+type typeChecker struct {
+	declared map[string]bool   // types declared at space level
+	imported map[string]bool   // spaces imported by the current file
+	local    []map[string]bool // types declared in the enclosing blocks so far
+	errors   []Error
+}
+
+func (c *typeChecker) file(f *ast.File) {
+	c.imported = make(map[string]bool)
+	for _, d := range f.DeclList {
+		if d, ok := d.(*ast.ImportDecl); ok {
+			if name := ImportName(d); name != "" {
+				c.imported[name] = true
+			}
+		}
+	}
+
+	for _, d := range f.DeclList {
+		c.decl(d)
+	}
+}
+
+func (c *typeChecker) decl(d ast.Decl) {
+	switch d := d.(type) {
+	case *ast.TypeDecl:
+		if n := len(c.local); n > 0 {
+			c.local[n-1][d.Name.Value] = true
+		}
+		c.typ(d.Type)
+	case *ast.ConstDecl:
+		c.typ(d.Type)
+	case *ast.VarDecl:
+		c.typ(d.Type)
+	case *ast.FuncDecl:
+		if d.Recv != nil {
+			c.typ(d.Recv.Type)
+		}
+		for _, f := range d.Param {
+			c.typ(f.Type)
+		}
+		c.typ(d.Return)
+		c.block(d.Body)
+	case *ast.OperDecl:
+		if d.TypeL != nil {
+			c.typ(d.TypeL.Type)
+		}
+		if d.TypeR != nil {
+			c.typ(d.TypeR.Type)
+		}
+		c.typ(d.Return)
+		c.block(d.Body)
+	}
+}
+
+func (c *typeChecker) block(b *ast.BlockStmt) {
+	if b == nil {
+		return
+	}
+	c.local = append(c.local, make(map[string]bool))
+	for _, s := range b.StmtList {
+		c.stmt(s)
+	}
+	c.local = c.local[:len(c.local)-1]
+}
+
+func (c *typeChecker) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.DeclStmt:
+		for _, d := range s.DeclList {
+			c.decl(d)
+		}
+	case *ast.BlockStmt:
+		c.block(s)
+	case *ast.IfStmt:
+		c.block(s.Block)
+		if s.Else != nil {
+			c.stmt(s.Else)
+		}
+	case *ast.ForStmt:
+		c.block(s.Body)
+	case *ast.WhileStmt:
+		c.block(s.Body)
+	case *ast.LabeledStmt:
+		c.stmt(s.Stmt)
+	case *ast.SwitchStmt:
+		for _, cc := range s.Body {
+			for _, s := range cc.Body {
+				c.stmt(s)
+			}
+		}
+	}
+}
+
+func (c *typeChecker) typ(x ast.Expr) {
+	switch x := x.(type) {
+	case *ast.Name:
+		if obj := Universe.Lookup(x.Value); (obj == nil || obj.Kind != TypeObj) && !c.declared[x.Value] && !c.isLocal(x.Value) {
+			c.errorf(x, "undefined type: "+x.Value)
+		}
+	case *ast.SelectorExpr:
+		if name, ok := x.X.(*ast.Name); !ok || !c.imported[name.Value] {
+			c.errorf(x, "undefined space in qualified type")
+		}
+	case *ast.SliceType:
+		c.typ(x.Elem)
+	case *ast.StructType:
+		for _, f := range x.FieldList {
+			c.typ(f.Type)
+		}
+	case *ast.MapType:
+		c.typ(x.Key)
+		c.typ(x.Value)
+	case *ast.PointerType:
+		c.typ(x.Elem)
+	case *ast.DotsType:
+		c.typ(x.Elem)
+	case *ast.ParenExpr:
+		c.typ(x.X)
+	case *ast.ListExpr:
+		for _, t := range x.ElemList {
+			c.typ(t)
+		}
+	}
+}
+
+// isLocal reports whether name is a type declared in an enclosing block.
+func (c *typeChecker) isLocal(name string) bool {
+	for _, m := range c.local {
+		if m[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *typeChecker) errorf(at ast.Node, msg string) {
+	c.errors = append(c.errors, Error{at.GetPos(), msg})
+}
+
+// ImportName returns the name under which the space imported by d is
+// referred to, that is its local name if any, or else the last element
+// of its import path, or "" if the path is missing or malformed.
+func ImportName(d *ast.ImportDecl) string {
+	if d.LocalName != nil {
+		return d.LocalName.Value
+	}
+	if d.Path == nil || d.Path.Bad {
+		return ""
+	}
+	p, err := strconv.Unquote(d.Path.Value)
+	if err != nil || p == "" {
+		return ""
+	}
+	return path.Base(p)
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package sema
+
+// Universe is the outermost scope. It holds the predeclared
+// types, constants and functions of the language.
+var Universe *Scope
+
+var predeclaredTypes = []string{
+	"bool",
+	"byte",
+	"rune",
+	"int",
+	"int8",
+	"int16",
+	"int32",
+	"int64",
+	"uint",
+	"uint8",
+	"uint16",
+	"uint32",
+	"uint64",
+	"float32",
+	"float64",
+	"string",
+}
+
+var predeclaredConsts = []string{
+	"true",
+	"false",
+}
+
+var predeclaredFuncs = []string{
+	"append",
+	"cap",
+	"copy",
+	"len",
+	"make",
+	"new",
+	"panic",
+	"print",
+	"println",
+}
+
+func init() {
+	Universe = NewScope(nil)
+	for _, name := range predeclaredTypes {
+		Universe.Insert(&Object{Kind: TypeObj, Name: name})
+	}
+	for _, name := range predeclaredConsts {
+		Universe.Insert(&Object{Kind: ConstObj, Name: name})
+	}
+	for _, name := range predeclaredFuncs {
+		Universe.Insert(&Object{Kind: BuiltinObj, Name: name})
+	}
+	Universe.Insert(&Object{Kind: NilObj, Name: "nil"})
+}
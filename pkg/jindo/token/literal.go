@@ -0,0 +1,20 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package token
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseFloat returns the value of the floating-point literal lit, as
+// accepted by the scanner: a decimal or hexadecimal mantissa, with an
+// optional exponent whose sign is kept ("1e+10", "1.5e-3", "0x1p-2"),
+// and possibly digits separated by underscores.
+func ParseFloat(lit string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(lit, "_", ""), 64)
+}
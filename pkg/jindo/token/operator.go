@@ -44,12 +44,21 @@ const (
 	Shl    // <<
 	Shr    // >>
 
-	Reverse //
+	numOperators // not an operator
 )
 
+// Reverse is or'ed into an overloadable operator to denote its reversed
+// form, as declared by an operator declaration such as "radd". It is a
+// bit above every operator, so reversed operators never collide with
+// forward ones.
+const Reverse Operator = 1 << 5
+
+const _ = Reverse - numOperators // Reverse must exceed every operator
+
 // Operator precedences
 const (
-	_ = iota
+	_         = iota
+	PrecRange // binds weaker than any binary operator: lo..hi
 	PrecOrOr
 	PrecAndAnd
 	PrecCmp
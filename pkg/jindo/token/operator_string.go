@@ -26,7 +26,7 @@ var opString = [...]string{
 	//Mul:    "*",
 	//Div:    "/",
 	Rem: "%",
-	//And:    "&",
+	And: "&",
 	//AndNot: "&^",
 	//Shl:    "<<",
 	//Shr:    ">>",
@@ -45,17 +45,19 @@ var opOverMap = map[string]Operator{
 	"gtr": Gtr,
 	"rem": Rem,
 
-	"rnot": Not + Reverse,
-	"radd": Add + Reverse,
-	"rsub": Sub + Reverse,
-	"rmul": Mul + Reverse,
-	"rdiv": Div + Reverse,
-	"reql": Eql + Reverse,
-	"rgtr": Gtr + Reverse,
-	"rrem": Rem + Reverse,
+	"rnot": Not | Reverse,
+	"radd": Add | Reverse,
+	"rsub": Sub | Reverse,
+	"rmul": Mul | Reverse,
+	"rdiv": Div | Reverse,
+	"reql": Eql | Reverse,
+	"rgtr": Gtr | Reverse,
+	"rrem": Rem | Reverse,
 }
 
-const operOverload = 1<<Not |
+// operOverload is the set of operators which may be overloaded,
+// including their reversed forms; bit op is set for operator op.
+const operOverload uint64 = 1<<Not |
 	1<<Add |
 	1<<Sub |
 	1<<Mul |
@@ -63,23 +65,38 @@ const operOverload = 1<<Not |
 	1<<Eql |
 	1<<Gtr |
 	1<<Rem |
-	1<<Not + Reverse |
-	1<<Add + Reverse |
-	1<<Sub + Reverse |
-	1<<Mul + Reverse |
-	1<<Div + Reverse |
-	1<<Eql + Reverse |
-	1<<Gtr + Reverse |
-	Rem + Reverse
+	1<<(Not|Reverse) |
+	1<<(Add|Reverse) |
+	1<<(Sub|Reverse) |
+	1<<(Mul|Reverse) |
+	1<<(Div|Reverse) |
+	1<<(Eql|Reverse) |
+	1<<(Gtr|Reverse) |
+	1<<(Rem|Reverse)
 
+// OperOrNil returns the operator overloaded by an operator
+// declaration named name, or NoneOp if name is not such a name.
 func OperOrNil(name string) Operator {
-	for s, t := range opOverMap {
-		if name == s {
-			return t
-		}
+	if op, ok := opOverMap[name]; ok {
+		return op
 	}
 	return NoneOp
 }
 
-func (op Operator) IsOperOverload() bool { return operOverload&op != 0 }
-func (op Operator) IsReversed() bool     { return op > Reverse }
+// OverloadName returns the name declaring an overload of op in an
+// operator declaration, such as "add" or "radd", or "" if there is none.
+func (op Operator) OverloadName() string {
+	for name, o := range opOverMap {
+		if o == op {
+			return name
+		}
+	}
+	return ""
+}
+
+func (op Operator) IsOperOverload() bool { return op < 64 && operOverload&(1<<op) != 0 }
+func (op Operator) IsReversed() bool     { return op&Reverse != 0 }
+
+// Forward returns the operator op is the reversed form of,
+// or op itself if it is not reversed.
+func (op Operator) Forward() Operator { return op &^ Reverse }
@@ -15,8 +15,11 @@ const (
 	EOF       // EOF
 
 	// names and literals
-	Name    // name
-	Literal // literal
+	Name       // name
+	Literal    // literal
+	StringHead // "...${
+	StringMid  // }...${
+	StringTail // }..."
 
 	// operators and operations
 	// Operator is excluding '*' (Star)
@@ -26,6 +29,7 @@ const (
 	Assign   // =
 	Define   // :=
 	Star     // *
+	Arrow    // <-
 
 	// delimiters
 	Lparen    // (
@@ -38,24 +42,31 @@ const (
 	Semi      // ;
 	Colon     // :
 	Dot       // .
+	DotDot    // ..
 	DotDotDot // ...
 
 	// keywords
 	keyword_beg
 	Break    // break
+	Case     // case
 	Const    // const
 	Continue // continue
-	While
-	Else   // else
-	For    // for
-	Func   // func
-	If     // if
-	Import // import
-	Space  // space
-	Return // return
-	Type   // type
-	Var    // var
-	Oper   // oper
+	Default  // default
+	While    // while
+	Else     // else
+	For      // for
+	Func     // func
+	Goto     // goto
+	If       // if
+	Import   // import
+	Map      // map
+	Space    // space
+	Return   // return
+	Struct   // struct
+	Switch   // switch
+	Type     // type
+	Var      // var
+	Oper     // oper
 	keyword_end
 
 	tokenCount
@@ -10,8 +10,11 @@ var tokenString = map[Token]string{
 	EOF: "fileOrEof",
 
 	// names and literals
-	Name:    "name",
-	Literal: "Literal",
+	Name:       "name",
+	Literal:    "Literal",
+	StringHead: "string head",
+	StringMid:  "string middle",
+	StringTail: "string tail",
 
 	// operators and operations
 	// Operator is excluding '*' (Star)
@@ -21,6 +24,7 @@ var tokenString = map[Token]string{
 	Assign:   "=",
 	Define:   ":=",
 	Star:     "*",
+	Arrow:    "<-",
 
 	// delimiters
 	Lparen:    "(",
@@ -33,6 +37,7 @@ var tokenString = map[Token]string{
 	Semi:      ";",
 	Colon:     ":",
 	Dot:       ".",
+	DotDot:    "..",
 	DotDotDot: "...",
 
 	Var:      "var",
@@ -44,19 +49,35 @@ var tokenString = map[Token]string{
 	Space:    "space",
 	Oper:     "oper",
 	Func:     "func",
+	Goto:     "goto",
 	Return:   "return",
 	For:      "for",
 	While:    "while",
 	Break:    "break",
 	Continue: "continue",
+	Switch:   "switch",
+	Struct:   "struct",
+	Map:      "map",
+	Case:     "case",
+	Default:  "default",
 }
 
 func (t Token) String() string { return tokenString[t] }
+
+// keywords maps each keyword to its token.
+var keywords = make(map[string]Token, keyword_end-keyword_beg-1)
+
+func init() {
+	for tok := keyword_beg + 1; tok < keyword_end; tok++ {
+		keywords[tokenString[tok]] = tok
+	}
+}
+
+// KeywordOrName returns the keyword token for lit,
+// or Name if lit is not a keyword.
 func KeywordOrName(lit string) Token {
-	for tok, k := range tokenString {
-		if k == lit {
-			return tok
-		}
+	if tok, ok := keywords[lit]; ok {
+		return tok
 	}
 	return Name
 }
@@ -0,0 +1,93 @@
+// Copyright 2024 The Jindo Authors. All rights reserved.
+// This file is part of jindo and is licensed under
+// the GNU General Public License version 3, which is available at
+// https://www.gnu.org/licenses/gpl-3.0.html or in the LICENSE file
+// located in the root directory of this source tree.
+
+package token
+
+import "testing"
+
+func TestKeywordOrName(t *testing.T) {
+	for tok := keyword_beg + 1; tok < keyword_end; tok++ {
+		if got := KeywordOrName(tok.String()); got != tok {
+			t.Errorf("KeywordOrName(%q) = %s, want %s", tok.String(), got, tok)
+		}
+	}
+
+	// non-keyword token strings are names
+	for _, lit := range []string{"name", "op", "opop", "fileOrEof", "foo"} {
+		if got := KeywordOrName(lit); got != Name {
+			t.Errorf("KeywordOrName(%q) = %s, want name", lit, got)
+		}
+	}
+}
+
+func TestIsOperOverload(t *testing.T) {
+	for name, op := range opOverMap {
+		if !op.IsOperOverload() {
+			t.Errorf("%s: IsOperOverload() = false, want true", name)
+		}
+		if got := OperOrNil(name); got != op {
+			t.Errorf("OperOrNil(%q) = %d, want %d", name, got, op)
+		}
+	}
+
+	for _, op := range []Operator{NoneOp, Def, OrOr, AndAnd, Neq, Lss, Leq, Geq, Or, Xor, And, AndNot, Shl, Shr, Reverse, Lss | Reverse} {
+		if op.IsOperOverload() {
+			t.Errorf("operator %d: IsOperOverload() = true, want false", op)
+		}
+	}
+	if got := OperOrNil("foo"); got != NoneOp {
+		t.Errorf("OperOrNil(%q) = %d, want NoneOp", "foo", got)
+	}
+}
+
+func TestReversed(t *testing.T) {
+	radd := OperOrNil("radd")
+	if !radd.IsReversed() || radd.Forward() != Add {
+		t.Errorf("radd: got %d (reversed: %v), want reversed Add", radd, radd.IsReversed())
+	}
+	if add := OperOrNil("add"); add != Add || add.IsReversed() || add.Forward() != Add {
+		t.Errorf("add: got %d (reversed: %v), want Add", add, add.IsReversed())
+	}
+
+	// reversed operators never collide with forward ones
+	for op := NoneOp; op < numOperators; op++ {
+		if op.IsReversed() {
+			t.Errorf("operator %d is reversed", op)
+		}
+		if r := op | Reverse; !r.IsReversed() || r.Forward() != op {
+			t.Errorf("operator %d: reversed form %d does not map back", op, r)
+		}
+	}
+}
+
+func TestParseFloat(t *testing.T) {
+	for _, test := range []struct {
+		lit  string
+		want float64
+	}{
+		{"1e+10", 1e10},
+		{"1e-10", 1e-10},
+		{"1.5e-3", 0.0015},
+		{"1.5E+3", 1500},
+		{"0x1p-2", 0.25},
+		{"0x1p+2", 4},
+		{"0X1.8P1", 3},
+		{"1_000.5e-1", 100.05},
+	} {
+		got, err := ParseFloat(test.lit)
+		if err != nil {
+			t.Errorf("%s: %v", test.lit, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseFloat(%q) = %g, want %g", test.lit, got, test.want)
+		}
+	}
+
+	if _, err := ParseFloat("1e"); err == nil {
+		t.Errorf("ParseFloat(%q) succeeded, want an error", "1e")
+	}
+}